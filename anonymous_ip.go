@@ -1,8 +1,6 @@
 package geoip2
 
-import "errors"
-
-func readAnonymousIPMap(result *AnonymousIP, buffer []byte, mapSize uint, offset uint) (uint, error) {
+func readAnonymousIPMap(result *AnonymousIP, buffer []byte, mapSize uint, offset uint, options *options) (uint, error) {
 	var key []byte
 	var err error
 	for i := uint(0); i < mapSize; i++ {
@@ -14,35 +12,38 @@ func readAnonymousIPMap(result *AnonymousIP, buffer []byte, mapSize uint, offset
 		case "is_anonymous":
 			result.IsAnonymous, offset, err = readBool(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "", key)
 			}
 		case "is_anonymous_vpn":
 			result.IsAnonymousVPN, offset, err = readBool(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "", key)
 			}
 		case "is_hosting_provider":
 			result.IsHostingProvider, offset, err = readBool(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "", key)
 			}
 		case "is_public_proxy":
 			result.IsPublicProxy, offset, err = readBool(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "", key)
 			}
 		case "is_tor_exit_node":
 			result.IsTorExitNode, offset, err = readBool(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "", key)
 			}
 		case "is_residential_proxy":
 			result.IsResidentialProxy, offset, err = readBool(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "", key)
 			}
 		default:
-			return 0, errors.New("unknown anonymous ip key: " + string(key))
+			offset, err = skipUnknownKey(options, "", key, buffer, offset)
+			if err != nil {
+				return 0, err
+			}
 		}
 	}
 	return offset, nil
@@ -1,18 +1,13 @@
 package geoip2
 
-import (
-	"errors"
-	"strconv"
-)
-
-func readCity(city *City, buffer []byte, offset uint) (uint, error) {
+func readCity(city *City, buffer []byte, offset uint, options *options) (uint, error) {
 	dataType, size, offset, err := readControl(buffer, offset)
 	if err != nil {
 		return 0, err
 	}
 	switch dataType {
 	case dataTypeMap:
-		return readCityMap(city, buffer, size, offset)
+		return readCityMap(city, buffer, size, offset, options)
 	case dataTypePointer:
 		pointer, newOffset, err := readPointer(buffer, size, offset)
 		if err != nil {
@@ -23,19 +18,19 @@ func readCity(city *City, buffer []byte, offset uint) (uint, error) {
 			return 0, err
 		}
 		if dataType != dataTypeMap {
-			return 0, errors.New("invalid city pointer type: " + strconv.Itoa(int(dataType)))
+			return 0, newUnexpectedTypeError("city", dataTypeMap, dataType)
 		}
-		_, err = readCityMap(city, buffer, size, offset)
+		_, err = readCityMap(city, buffer, size, offset, options)
 		if err != nil {
 			return 0, err
 		}
 		return newOffset, nil
 	default:
-		return 0, errors.New("invalid city type: " + strconv.Itoa(int(dataType)))
+		return 0, newUnexpectedTypeError("city", dataTypeMap, dataType)
 	}
 }
 
-func readCityMap(city *City, buffer []byte, mapSize uint, offset uint) (uint, error) {
+func readCityMap(city *City, buffer []byte, mapSize uint, offset uint, options *options) (uint, error) {
 	var key []byte
 	var err error
 	for i := uint(0); i < mapSize; i++ {
@@ -47,21 +42,32 @@ func readCityMap(city *City, buffer []byte, mapSize uint, offset uint) (uint, er
 		case "geoname_id":
 			city.GeoNameID, offset, err = readUInt32(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "city", key)
 			}
 		case "names":
-			city.Names, offset, err = readStringMap(buffer, offset)
+			city.Names, offset, err = readStringMap(city.Names, buffer, offset, options.languages)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "city", key)
 			}
 		case "confidence":
 			city.Confidence, offset, err = readUInt16(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "city", key)
 			}
 		default:
-			return 0, errors.New("unknown city key: " + string(key))
+			offset, err = skipUnknownKey(options, "city", key, buffer, offset)
+			if err != nil {
+				return 0, err
+			}
 		}
 	}
 	return offset, nil
 }
+
+func (city *City) reset() {
+	names := city.Names
+	clearStringMap(names)
+	*city = City{
+		Names: names,
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/IncSW/geoip2"
+)
+
+const exportUsage = "export [-format csv|jsonl] [-ip-version 4|6] [-locations file.csv] [-language en] file.mmdb"
+
+// The columns of the GeoLite2 CSV databases.
+var (
+	cityBlocksHeader = []string{
+		"network", "geoname_id", "registered_country_geoname_id", "represented_country_geoname_id",
+		"is_anonymous_proxy", "is_satellite_provider", "postal_code", "latitude", "longitude", "accuracy_radius",
+		"is_anycast",
+	}
+	countryBlocksHeader = []string{
+		"network", "geoname_id", "registered_country_geoname_id", "represented_country_geoname_id",
+		"is_anonymous_proxy", "is_satellite_provider", "is_anycast",
+	}
+	asnBlocksHeader = []string{
+		"network", "autonomous_system_number", "autonomous_system_organization",
+	}
+	cityLocationsHeader = []string{
+		"geoname_id", "locale_code", "continent_code", "continent_name", "country_iso_code", "country_name",
+		"subdivision_1_iso_code", "subdivision_1_name", "subdivision_2_iso_code", "subdivision_2_name",
+		"city_name", "metro_code", "time_zone", "is_in_european_union",
+	}
+	countryLocationsHeader = []string{
+		"geoname_id", "locale_code", "continent_code", "continent_name", "country_iso_code", "country_name",
+		"is_in_european_union",
+	}
+)
+
+type exportRecord struct {
+	Network string      `json:"network"`
+	Record  interface{} `json:"record"`
+}
+
+// export writes every network of a database and its record to stdout, as CSV
+// in the layout of the GeoLite2 CSV databases or as JSON lines.
+func export(env *env, args []string) error {
+	flags := newFlagSet(env, "export", exportUsage)
+	format := flags.String("format", "csv", "output format: csv or jsonl")
+	ipVersion := flags.Int("ip-version", 0, "export only the IPv4 (4) or the IPv6 (6) networks")
+	locationsFile := flags.String("locations", "", "write the locations of a City or Country database as CSV to this file")
+	language := flags.String("language", "en", "language of the names in the locations")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || (*format != "csv" && *format != "jsonl") || (*ipVersion != 0 && *ipVersion != 4 && *ipVersion != 6) {
+		return errUsage
+	}
+	if *format == "jsonl" && *locationsFile != "" {
+		return errUsage
+	}
+	reader, err := geoip2.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	include := func(network netip.Prefix) bool {
+		switch *ipVersion {
+		case 4:
+			return network.Addr().Is4()
+		case 6:
+			return !network.Addr().Is4()
+		}
+		return true
+	}
+
+	if *format == "jsonl" {
+		return exportJSONL(env.stdout, reader, include)
+	}
+	var locations *locationTable
+	if *locationsFile != "" {
+		if !hasLanguage(reader.Metadata(), *language) {
+			return errors.New("the database has no names in language " + *language)
+		}
+		locations = &locationTable{
+			language: *language,
+			rows:     map[uint32][]string{},
+		}
+	}
+	err = exportCSV(env.stdout, reader, include, locations)
+	if err != nil || locations == nil {
+		return err
+	}
+	file, err := os.Create(*locationsFile)
+	if err != nil {
+		return err
+	}
+	err = locations.writeTo(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func exportJSONL(writer io.Writer, reader *geoip2.Reader, include func(netip.Prefix) bool) error {
+	buffer := bufio.NewWriter(writer)
+	encoder := json.NewEncoder(buffer)
+	networks := reader.Networks()
+	for networks.Next() {
+		if !include(networks.Network()) {
+			continue
+		}
+		record, err := networks.Record()
+		if err != nil {
+			return err
+		}
+		err = encoder.Encode(exportRecord{
+			Network: networks.Network().String(),
+			Record:  record,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err := networks.Err()
+	if err != nil {
+		return err
+	}
+	return buffer.Flush()
+}
+
+func exportCSV(writer io.Writer, reader *geoip2.Reader, include func(netip.Prefix) bool, locations *locationTable) error {
+	out := csv.NewWriter(writer)
+	var err error
+	if city, cityErr := reader.City(); cityErr == nil {
+		locations.setHeader(cityLocationsHeader)
+		err = writeBlocks(out, city.Networks(), include, cityBlocksHeader, func(network netip.Prefix, record *geoip2.CityResult) []string {
+			locations.addCity(record)
+			geoNameID := record.City.GeoNameID
+			if geoNameID == 0 {
+				geoNameID = record.Country.GeoNameID
+			}
+			latitude, longitude := "", ""
+			if record.Location.Latitude != 0 || record.Location.Longitude != 0 {
+				latitude = strconv.FormatFloat(record.Location.Latitude, 'f', -1, 64)
+				longitude = strconv.FormatFloat(record.Location.Longitude, 'f', -1, 64)
+			}
+			return []string{
+				network.String(),
+				formatID(geoNameID),
+				formatID(record.RegisteredCountry.GeoNameID),
+				formatID(record.RepresentedCountry.GeoNameID),
+				formatBool(record.Traits.IsAnonymousProxy),
+				formatBool(record.Traits.IsSatelliteProvider),
+				record.Postal.Code,
+				latitude,
+				longitude,
+				formatID(uint32(record.Location.AccuracyRadius)),
+				formatBool(record.Traits.IsAnycast),
+			}
+		})
+	} else if country, countryErr := reader.Country(); countryErr == nil {
+		locations.setHeader(countryLocationsHeader)
+		err = writeBlocks(out, country.Networks(), include, countryBlocksHeader, func(network netip.Prefix, record *geoip2.CountryResult) []string {
+			locations.addContinent(record.Continent)
+			locations.addCountry(record.Continent, record.Country)
+			locations.addCountry(geoip2.Continent{}, record.RegisteredCountry)
+			locations.addCountry(geoip2.Continent{}, record.RepresentedCountry)
+			return []string{
+				network.String(),
+				formatID(record.Country.GeoNameID),
+				formatID(record.RegisteredCountry.GeoNameID),
+				formatID(record.RepresentedCountry.GeoNameID),
+				formatBool(record.Traits.IsAnonymousProxy),
+				formatBool(record.Traits.IsSatelliteProvider),
+				formatBool(record.Traits.IsAnycast),
+			}
+		})
+	} else if asn, asnErr := reader.ASN(); asnErr == nil {
+		if locations != nil {
+			return errors.New("locations are only exported from City and Country databases")
+		}
+		err = writeBlocks(out, asn.Networks(), include, asnBlocksHeader, func(network netip.Prefix, record *geoip2.ASN) []string {
+			return []string{
+				network.String(),
+				formatID(record.AutonomousSystemNumber),
+				record.AutonomousSystemOrganization,
+			}
+		})
+	} else {
+		return errors.New("CSV export of " + reader.Metadata().DatabaseType + " databases is not supported, use -format jsonl")
+	}
+	if err != nil {
+		return err
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func writeBlocks[T any](out *csv.Writer, networks *geoip2.Networks[T], include func(netip.Prefix) bool, header []string, row func(network netip.Prefix, record T) []string) error {
+	err := out.Write(header)
+	if err != nil {
+		return err
+	}
+	for networks.Next() {
+		if !include(networks.Network()) {
+			continue
+		}
+		record, err := networks.Record()
+		if err != nil {
+			return err
+		}
+		err = out.Write(row(networks.Network(), record))
+		if err != nil {
+			return err
+		}
+	}
+	return networks.Err()
+}
+
+// locationTable collects the rows of a GeoLite2 Locations file keyed by their
+// GeoNames ID. A nil locationTable collects nothing.
+type locationTable struct {
+	language string
+	header   []string
+	rows     map[uint32][]string
+}
+
+func (l *locationTable) setHeader(header []string) {
+	if l != nil {
+		l.header = header
+	}
+}
+
+func (l *locationTable) addCity(record *geoip2.CityResult) {
+	if l == nil {
+		return
+	}
+	l.addContinent(record.Continent)
+	l.addCountry(record.Continent, record.Country)
+	l.addCountry(geoip2.Continent{}, record.RegisteredCountry)
+	l.addCountry(geoip2.Continent{}, record.RepresentedCountry)
+	if record.City.GeoNameID == 0 {
+		return
+	}
+	var subdivisions [2]geoip2.Subdivision
+	copy(subdivisions[:], record.Subdivisions)
+	metroCode := ""
+	if record.Location.MetroCode != 0 {
+		metroCode = strconv.Itoa(int(record.Location.MetroCode))
+	}
+	l.add(record.City.GeoNameID, []string{
+		formatID(record.City.GeoNameID),
+		l.language,
+		record.Continent.Code,
+		record.Continent.Names[l.language],
+		record.Country.ISOCode,
+		record.Country.Names[l.language],
+		subdivisions[0].ISOCode,
+		subdivisions[0].Names[l.language],
+		subdivisions[1].ISOCode,
+		subdivisions[1].Names[l.language],
+		record.City.Names[l.language],
+		metroCode,
+		record.Location.TimeZone,
+		formatBool(record.Country.IsInEuropeanUnion),
+	})
+}
+
+func (l *locationTable) addContinent(continent geoip2.Continent) {
+	if l == nil || continent.GeoNameID == 0 {
+		return
+	}
+	row := make([]string, len(l.header))
+	row[0] = formatID(continent.GeoNameID)
+	row[1] = l.language
+	row[2] = continent.Code
+	row[3] = continent.Names[l.language]
+	row[len(row)-1] = formatBool(false)
+	l.add(continent.GeoNameID, row)
+}
+
+// addCountry adds the row of a country. Registered and represented countries
+// come without their continent, so rows with a continent take precedence.
+func (l *locationTable) addCountry(continent geoip2.Continent, country geoip2.Country) {
+	if l == nil || country.GeoNameID == 0 {
+		return
+	}
+	if existing, ok := l.rows[country.GeoNameID]; ok && (existing[2] != "" || continent.Code == "") {
+		return
+	}
+	row := make([]string, len(l.header))
+	row[0] = formatID(country.GeoNameID)
+	row[1] = l.language
+	row[2] = continent.Code
+	row[3] = continent.Names[l.language]
+	row[4] = country.ISOCode
+	row[5] = country.Names[l.language]
+	row[len(row)-1] = formatBool(country.IsInEuropeanUnion)
+	l.rows[country.GeoNameID] = row
+}
+
+func (l *locationTable) add(geoNameID uint32, row []string) {
+	if _, ok := l.rows[geoNameID]; !ok {
+		l.rows[geoNameID] = row
+	}
+}
+
+func (l *locationTable) writeTo(writer io.Writer) error {
+	ids := make([]uint32, 0, len(l.rows))
+	for id := range l.rows {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	out := csv.NewWriter(writer)
+	err := out.Write(l.header)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		err = out.Write(l.rows[id])
+		if err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func hasLanguage(metadata *geoip2.Metadata, language string) bool {
+	for _, current := range metadata.Languages {
+		if current == language {
+			return true
+		}
+	}
+	return false
+}
+
+func formatID(id uint32) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func formatBool(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
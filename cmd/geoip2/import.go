@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/IncSW/geoip2"
+	"github.com/IncSW/geoip2/writer"
+)
+
+const importUsage = "import -type GeoLite2-City -o file.mmdb [-description text] [-record-size 24|28|32] -locations Locations-en.csv... Blocks.csv..."
+
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// importCSV writes a City or Country database from the Locations and Blocks
+// files of a database in the MaxMind CSV format.
+func importCSV(env *env, args []string) error {
+	flags := newFlagSet(env, "import", importUsage)
+	databaseType := flags.String("type", "", "database type, e.g. GeoLite2-City or GeoLite2-Country")
+	output := flags.String("o", "", "write the database to this file")
+	description := flags.String("description", "", "English description of the database")
+	recordSize := flags.Uint("record-size", 28, "record size: 24, 28 or 32")
+	var locations stringsFlag
+	flags.Var(&locations, "locations", "read locations from this file, once per language")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if *databaseType == "" || *output == "" || flags.NArg() == 0 {
+		return errUsage
+	}
+	if *recordSize != 24 && *recordSize != 28 && *recordSize != 32 {
+		return errUsage
+	}
+	metadata := geoip2.Metadata{
+		DatabaseType: *databaseType,
+		RecordSize:   uint16(*recordSize),
+	}
+	if *description != "" {
+		metadata.Description = map[string]string{"en": *description}
+	}
+	w, err := writer.New(metadata)
+	if err != nil {
+		return err
+	}
+	importer := writer.NewCSVImporter(w)
+	for _, filename := range locations {
+		err = readCSVFile(filename, importer.ReadLocations)
+		if err != nil {
+			return err
+		}
+	}
+	for _, filename := range flags.Args() {
+		err = readCSVFile(filename, importer.InsertBlocks)
+		if err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	_, err = w.WriteTo(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func readCSVFile(filename string, read func(reader io.Reader) error) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	err = read(file)
+	if err != nil {
+		return errors.New(filename + ": " + err.Error())
+	}
+	return nil
+}
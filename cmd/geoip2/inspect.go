@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/IncSW/geoip2"
+)
+
+const inspectUsage = "inspect [-format table|json] file.mmdb"
+
+type inspectResult struct {
+	Metadata        *geoip2.Metadata `json:"metadata"`
+	BuildTime       time.Time        `json:"build_time"`
+	SearchTreeSize  int              `json:"search_tree_size"`
+	DataSectionSize int              `json:"data_section_size"`
+	Records         int              `json:"records"`
+	Networks        int              `json:"networks"`
+	IPv4Networks    map[int]int      `json:"ipv4_networks"`
+	IPv6Networks    map[int]int      `json:"ipv6_networks"`
+	IPv4Addresses   *big.Int         `json:"ipv4_addresses"`
+	IPv6Addresses   *big.Int         `json:"ipv6_addresses"`
+}
+
+// inspect prints the metadata of a database and statistics of its networks.
+func inspect(env *env, args []string) error {
+	flags := newFlagSet(env, "inspect", inspectUsage)
+	format := flags.String("format", "table", "output format: table or json")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || (*format != "table" && *format != "json") {
+		return errUsage
+	}
+	reader, err := geoip2.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	metadata := reader.Metadata()
+	result := &inspectResult{
+		Metadata:        metadata,
+		BuildTime:       metadata.BuildTime(),
+		SearchTreeSize:  reader.SearchTreeSize(),
+		DataSectionSize: reader.DataSectionSize(),
+		IPv4Networks:    map[int]int{},
+		IPv6Networks:    map[int]int{},
+		IPv4Addresses:   new(big.Int),
+		IPv6Addresses:   new(big.Int),
+	}
+	records := map[uint]struct{}{}
+	networks := reader.Networks()
+	for networks.Next() {
+		network := networks.Network()
+		records[networks.Offset()] = struct{}{}
+		result.Networks++
+		size := new(big.Int).Lsh(big.NewInt(1), uint(network.Addr().BitLen()-network.Bits()))
+		if network.Addr().Is4() {
+			result.IPv4Networks[network.Bits()]++
+			result.IPv4Addresses.Add(result.IPv4Addresses, size)
+		} else {
+			result.IPv6Networks[network.Bits()]++
+			result.IPv6Addresses.Add(result.IPv6Addresses, size)
+		}
+	}
+	err = networks.Err()
+	if err != nil {
+		return err
+	}
+	result.Records = len(records)
+
+	if *format == "json" {
+		encoder := json.NewEncoder(env.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	return printInspectResult(env, result)
+}
+
+func printInspectResult(env *env, result *inspectResult) error {
+	metadata := result.Metadata
+	tab := tabwriter.NewWriter(env.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tab, "Database type\t%s\n", metadata.DatabaseType)
+	fmt.Fprintf(tab, "IP version\t%d\n", metadata.IPVersion)
+	fmt.Fprintf(tab, "Record size\t%d\n", metadata.RecordSize)
+	fmt.Fprintf(tab, "Node count\t%d\n", metadata.NodeCount)
+	fmt.Fprintf(tab, "Binary format\t%d.%d\n", metadata.BinaryFormatMajorVersion, metadata.BinaryFormatMinorVersion)
+	fmt.Fprintf(tab, "Languages\t%s\n", strings.Join(metadata.Languages, ", "))
+	languages := make([]string, 0, len(metadata.Description))
+	for language := range metadata.Description {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		fmt.Fprintf(tab, "Description (%s)\t%s\n", language, metadata.Description[language])
+	}
+	fmt.Fprintf(tab, "Build time\t%s (%d days ago)\n", result.BuildTime.Format(time.RFC3339), int(metadata.Age().Hours()/24))
+	fmt.Fprintf(tab, "Search tree size\t%d bytes\n", result.SearchTreeSize)
+	fmt.Fprintf(tab, "Data section size\t%d bytes\n", result.DataSectionSize)
+	fmt.Fprintf(tab, "Data records\t%d\n", result.Records)
+	fmt.Fprintf(tab, "Networks\t%d\n", result.Networks)
+	fmt.Fprintf(tab, "IPv4 coverage\t%s\n", coverage(result.IPv4Addresses, 32))
+	if metadata.IPVersion == 6 {
+		fmt.Fprintf(tab, "IPv6 coverage\t%s\n", coverage(result.IPv6Addresses, 128))
+	}
+	err := tab.Flush()
+	if err != nil {
+		return err
+	}
+
+	if result.Networks == 0 {
+		return nil
+	}
+	fmt.Fprintln(env.stdout)
+	tab = tabwriter.NewWriter(env.stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tab, "Prefix length\tIPv4\tIPv6\t\n")
+	for bits := 0; bits <= 128; bits++ {
+		ipv4, ipv6 := result.IPv4Networks[bits], result.IPv6Networks[bits]
+		if ipv4 != 0 || ipv6 != 0 {
+			fmt.Fprintf(tab, "/%d\t%d\t%d\t\n", bits, ipv4, ipv6)
+		}
+	}
+	return tab.Flush()
+}
+
+// coverage formats the number of addresses and their share of the address
+// space.
+func coverage(addresses *big.Int, bitCount uint) string {
+	share, _ := new(big.Float).Quo(new(big.Float).SetInt(addresses), new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), bitCount))).Float64()
+	if addresses.BitLen() > 53 {
+		number, _ := new(big.Float).SetInt(addresses).Float64()
+		return fmt.Sprintf("%.3g addresses (%.3g%%)", number, share*100)
+	}
+	return fmt.Sprintf("%s addresses (%.3g%%)", addresses, share*100)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/IncSW/geoip2"
+)
+
+const lookupUsage = "lookup [-format table|json] file.mmdb [ip...]"
+
+type lookupFunc func(addr netip.Addr) (interface{}, netip.Prefix, error)
+
+type lookupResult struct {
+	IP      string      `json:"ip"`
+	Network string      `json:"network,omitempty"`
+	Record  interface{} `json:"record,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// lookup prints the records of the IP addresses given as arguments, or read
+// from stdin one per line.
+func lookup(env *env, args []string) error {
+	flags := newFlagSet(env, "lookup", lookupUsage)
+	format := flags.String("format", "table", "output format: table or json")
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 || (*format != "table" && *format != "json") {
+		return errUsage
+	}
+	reader, err := geoip2.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	lookup := newLookupFunc(reader)
+
+	ips := flags.Args()[1:]
+	if len(ips) == 0 {
+		scanner := bufio.NewScanner(env.stdin)
+		for scanner.Scan() {
+			ip := strings.TrimSpace(scanner.Text())
+			if ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+		err = scanner.Err()
+		if err != nil {
+			return err
+		}
+	}
+	failed := 0
+	encoder := json.NewEncoder(env.stdout)
+	encoder.SetIndent("", "  ")
+	for _, ip := range ips {
+		result := lookupResult{
+			IP: ip,
+		}
+		addr, err := netip.ParseAddr(ip)
+		if err == nil {
+			var network netip.Prefix
+			result.Record, network, err = lookup(addr)
+			if network.IsValid() {
+				result.Network = network.String()
+			}
+		}
+		if err != nil {
+			result.Error = err.Error()
+			failed++
+		}
+		if *format == "json" {
+			err = encoder.Encode(result)
+		} else {
+			err = printTable(env.stdout, result)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if failed != 0 {
+		return fmt.Errorf("%d of %d lookups failed", failed, len(ips))
+	}
+	return nil
+}
+
+// newLookupFunc returns a lookup using the typed reader of the database type,
+// or decoding records into maps for other types.
+func newLookupFunc(reader *geoip2.Reader) lookupFunc {
+	if city, err := reader.City(); err == nil {
+		return func(addr netip.Addr) (interface{}, netip.Prefix, error) {
+			result, err := city.LookupAddr(addr)
+			if err != nil {
+				return nil, netip.Prefix{}, err
+			}
+			return result, result.Prefix, nil
+		}
+	}
+	if country, err := reader.Country(); err == nil {
+		return func(addr netip.Addr) (interface{}, netip.Prefix, error) {
+			result, err := country.LookupAddr(addr)
+			if err != nil {
+				return nil, netip.Prefix{}, err
+			}
+			return result, result.Prefix, nil
+		}
+	}
+	if isp, err := reader.ISP(); err == nil {
+		return func(addr netip.Addr) (interface{}, netip.Prefix, error) {
+			result, err := isp.LookupAddr(addr)
+			if err != nil {
+				return nil, netip.Prefix{}, err
+			}
+			return result, result.Prefix, nil
+		}
+	}
+	if asn, err := reader.ASN(); err == nil {
+		return func(addr netip.Addr) (interface{}, netip.Prefix, error) {
+			result, err := asn.LookupAddr(addr)
+			if err != nil {
+				return nil, netip.Prefix{}, err
+			}
+			return result, result.Prefix, nil
+		}
+	}
+	if anonymousIP, err := reader.AnonymousIP(); err == nil {
+		return func(addr netip.Addr) (interface{}, netip.Prefix, error) {
+			result, err := anonymousIP.LookupAddr(addr)
+			if err != nil {
+				return nil, netip.Prefix{}, err
+			}
+			return result, result.Prefix, nil
+		}
+	}
+	if connectionType, err := reader.ConnectionType(); err == nil {
+		return func(addr netip.Addr) (interface{}, netip.Prefix, error) {
+			return connectionType.LookupAddrWithPrefix(addr)
+		}
+	}
+	if domain, err := reader.Domain(); err == nil {
+		return func(addr netip.Addr) (interface{}, netip.Prefix, error) {
+			return domain.LookupAddrWithPrefix(addr)
+		}
+	}
+	return func(addr netip.Addr) (interface{}, netip.Prefix, error) {
+		offset, prefix, err := reader.LookupAddrOffset(addr)
+		if err != nil {
+			return nil, netip.Prefix{}, err
+		}
+		var result interface{}
+		err = reader.Decode(offset, &result)
+		if err != nil {
+			return nil, netip.Prefix{}, err
+		}
+		return result, prefix, nil
+	}
+}
+
+func printTable(writer io.Writer, result lookupResult) error {
+	if result.Error != "" {
+		_, err := fmt.Fprintf(writer, "%s  %s\n", result.IP, result.Error)
+		return err
+	}
+	_, err := fmt.Fprintf(writer, "%s  %s\n", result.IP, result.Network)
+	if err != nil {
+		return err
+	}
+	tab := tabwriter.NewWriter(writer, 0, 8, 2, ' ', 0)
+	flatten("", reflect.ValueOf(result.Record), func(key string, value string) {
+		switch key {
+		case "":
+			key = "record"
+		case "Prefix":
+			return
+		}
+		fmt.Fprintf(tab, "  %s\t%s\n", key, value)
+	})
+	return tab.Flush()
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// flatten calls add with the path and the value of every non-zero scalar of
+// value, e.g. "City.Names.en".
+func flatten(path string, value reflect.Value, add func(key string, value string)) {
+	if !value.IsValid() || value.IsZero() {
+		return
+	}
+	if value.Type().Implements(stringerType) {
+		add(path, value.Interface().(fmt.Stringer).String())
+		return
+	}
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		flatten(path, value.Elem(), add)
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).PkgPath == "" {
+				flatten(join(path, value.Type().Field(i).Name), value.Field(i), add)
+			}
+		}
+	case reflect.Map:
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			flatten(join(path, fmt.Sprint(key.Interface())), value.MapIndex(key), add)
+		}
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			add(path, fmt.Sprintf("%x", value.Interface()))
+			return
+		}
+		for i := 0; i < value.Len(); i++ {
+			flatten(join(path, fmt.Sprint(i)), value.Index(i), add)
+		}
+	default:
+		add(path, fmt.Sprint(value.Interface()))
+	}
+}
+
+func join(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
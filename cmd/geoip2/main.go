@@ -0,0 +1,109 @@
+// Command geoip2 looks up IP addresses in, inspects, exports and imports
+// MaxMind DB files.
+//
+// Usage:
+//
+//	geoip2 <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	lookup	print the records of IP addresses
+//	inspect	print the metadata and statistics of a database
+//	export	print every network of a database as CSV or JSON lines
+//	import	write a database from the MaxMind CSV format
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	run   func(env *env, args []string) error
+	usage string
+}
+
+var commands = map[string]command{
+	"lookup":  {lookup, lookupUsage},
+	"inspect": {inspect, inspectUsage},
+	"export":  {export, exportUsage},
+	"import":  {importCSV, importUsage},
+}
+
+func main() {
+	os.Exit(run(&env{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}, os.Args[1:]))
+}
+
+func run(env *env, args []string) int {
+	if len(args) == 0 {
+		usage(env.stderr)
+		return 2
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintln(env.stderr, "geoip2: unknown command:", args[0])
+		usage(env.stderr)
+		return 2
+	}
+	err := command.run(env, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if errors.Is(err, errUsageReported) {
+		return 2
+	}
+	if errors.Is(err, errUsage) {
+		fmt.Fprintln(env.stderr, "usage: geoip2", command.usage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(env.stderr, "geoip2 "+args[0]+":", err)
+		return 1
+	}
+	return 0
+}
+
+var (
+	errUsage         = errors.New("usage")
+	errUsageReported = errors.New("usage reported")
+)
+
+func usage(writer io.Writer) {
+	fmt.Fprintln(writer, "usage:")
+	for _, name := range []string{"lookup", "inspect", "export", "import"} {
+		fmt.Fprintln(writer, "\tgeoip2", commands[name].usage)
+	}
+}
+
+func newFlagSet(env *env, name string, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(env.stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(env.stderr, "usage: geoip2", usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses args and returns errUsage for invalid flags, which the
+// flag set has already reported.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return errUsageReported
+	}
+	return err
+}
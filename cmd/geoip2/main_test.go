@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IncSW/geoip2"
+	"github.com/IncSW/geoip2/writer"
+)
+
+func writeTestDatabase(t *testing.T, databaseType string, networks map[string]interface{}) string {
+	w, err := writer.New(geoip2.Metadata{
+		DatabaseType: databaseType,
+		Languages:    []string{"en"},
+		Description:  map[string]string{"en": "Test database"},
+		BuildEpoch:   1700000000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for network, record := range networks {
+		err = w.Insert(netip.MustParsePrefix(network), record)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	filename := filepath.Join(t.TempDir(), databaseType+".mmdb")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.WriteTo(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+var testCityNetworks = map[string]interface{}{
+	"81.2.69.128/26": map[string]interface{}{
+		"city": map[string]interface{}{
+			"geoname_id": uint32(2643743),
+			"names":      map[string]string{"en": "London"},
+		},
+		"country": map[string]interface{}{
+			"geoname_id": uint32(2635167),
+			"iso_code":   "GB",
+			"names":      map[string]string{"en": "United Kingdom"},
+		},
+		"location": map[string]interface{}{
+			"latitude":  51.5142,
+			"longitude": -0.0931,
+			"time_zone": "Europe/London",
+		},
+	},
+	"2a02:ff80::/29": map[string]interface{}{
+		"country": map[string]interface{}{
+			"geoname_id": uint32(2921044),
+			"iso_code":   "DE",
+			"names":      map[string]string{"en": "Germany"},
+		},
+		"traits": map[string]interface{}{
+			"is_anycast": true,
+		},
+	},
+}
+
+func runCommand(t *testing.T, stdin string, args ...string) (int, string, string) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := run(&env{
+		stdin:  strings.NewReader(stdin),
+		stdout: stdout,
+		stderr: stderr,
+	}, args)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestLookup(t *testing.T) {
+	filename := writeTestDatabase(t, "GeoIP2-City", testCityNetworks)
+
+	code, stdout, stderr := runCommand(t, "", "lookup", "-format", "json", filename, "81.2.69.142", "2a02:ff80::1")
+	if code != 0 {
+		t.Fatal(code, stderr)
+	}
+	decoder := json.NewDecoder(strings.NewReader(stdout))
+	var result struct {
+		IP      string
+		Network string
+		Record  geoip2.CityResult
+	}
+	err := decoder.Decode(&result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IP != "81.2.69.142" || result.Network != "81.2.69.128/26" || result.Record.City.Names["en"] != "London" {
+		t.Fatal(result)
+	}
+	err = decoder.Decode(&result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Network != "2a02:ff80::/29" || result.Record.Country.ISOCode != "DE" {
+		t.Fatal(result)
+	}
+
+	code, stdout, _ = runCommand(t, "81.2.69.142\n\n1.1.1.1\n", "lookup", filename)
+	if code != 1 {
+		t.Fatal(code)
+	}
+	for _, line := range []string{
+		"81.2.69.142  81.2.69.128/26\n",
+		"  City.Names.en       London\n",
+		"  Location.TimeZone   Europe/London\n",
+		"1.1.1.1  not found\n",
+	} {
+		if !strings.Contains(stdout, line) {
+			t.Fatal(stdout)
+		}
+	}
+
+	filename = writeTestDatabase(t, "Office-Networks", map[string]interface{}{
+		"10.0.0.0/16": map[string]interface{}{"office": "Berlin", "vlan": uint16(42)},
+	})
+	code, stdout, stderr = runCommand(t, "", "lookup", filename, "10.0.1.1")
+	if code != 0 {
+		t.Fatal(code, stderr)
+	}
+	if stdout != "10.0.1.1  10.0.0.0/16\n  office  Berlin\n  vlan    42\n" {
+		t.Fatal(stdout)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"unknown"},
+		{"lookup"},
+		{"lookup", "-format", "xml", "file.mmdb"},
+		{"lookup", "-unknown"},
+		{"import", "-type", "GeoLite2-City", "-o", "file.mmdb", "-record-size", "65560", "blocks.csv"},
+	} {
+		code, _, stderr := runCommand(t, "", args...)
+		if code != 2 || !strings.Contains(stderr, "usage") {
+			t.Fatal(args, code, stderr)
+		}
+	}
+	code, _, stderr := runCommand(t, "", "lookup", "missing.mmdb", "1.1.1.1")
+	if code != 1 || !strings.HasPrefix(stderr, "geoip2 lookup: ") {
+		t.Fatal(code, stderr)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	filename := writeTestDatabase(t, "GeoIP2-City", testCityNetworks)
+
+	code, stdout, stderr := runCommand(t, "", "inspect", filename)
+	if code != 0 {
+		t.Fatal(code, stderr)
+	}
+	for _, line := range []string{
+		"Database type      GeoIP2-City\n",
+		"Languages          en\n",
+		"Description (en)   Test database\n",
+		"Build time         2023-11-14T22:13:20Z",
+		"Data records       2\n",
+		"Networks           2\n",
+		"IPv4 coverage      64 addresses (1.49e-06%)\n",
+		"IPv6 coverage      6.34e+29 addresses (1.86e-07%)\n",
+		"          /26     1     0\n",
+		"          /29     0     1\n",
+	} {
+		if !strings.Contains(stdout, line) {
+			t.Fatal(stdout)
+		}
+	}
+
+	code, stdout, stderr = runCommand(t, "", "inspect", "-format", "json", filename)
+	if code != 0 {
+		t.Fatal(code, stderr)
+	}
+	var result struct {
+		Metadata struct {
+			DatabaseType string
+			NodeCount    int
+		} `json:"metadata"`
+		Records      int         `json:"records"`
+		IPv6Networks map[int]int `json:"ipv6_networks"`
+	}
+	err := json.Unmarshal([]byte(stdout), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Metadata.DatabaseType != "GeoIP2-City" || result.Metadata.NodeCount == 0 || result.Records != 2 || result.IPv6Networks[29] != 1 {
+		t.Fatal(stdout)
+	}
+}
+
+func TestExport(t *testing.T) {
+	filename := writeTestDatabase(t, "GeoIP2-City", testCityNetworks)
+	locations := filepath.Join(t.TempDir(), "locations.csv")
+
+	code, stdout, stderr := runCommand(t, "", "export", "-locations", locations, filename)
+	if code != 0 {
+		t.Fatal(code, stderr)
+	}
+	if stdout != "network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider,postal_code,latitude,longitude,accuracy_radius,is_anycast\n"+
+		"81.2.69.128/26,2643743,,,0,0,,51.5142,-0.0931,,0\n"+
+		"2a02:ff80::/29,2921044,,,0,0,,,,,1\n" {
+		t.Fatal(stdout)
+	}
+	data, err := os.ReadFile(locations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,subdivision_1_iso_code,subdivision_1_name,subdivision_2_iso_code,subdivision_2_name,city_name,metro_code,time_zone,is_in_european_union\n"+
+		"2635167,en,,,GB,United Kingdom,,,,,,,,0\n"+
+		"2643743,en,,,GB,United Kingdom,,,,,London,,Europe/London,0\n"+
+		"2921044,en,,,DE,Germany,,,,,,,,0\n" {
+		t.Fatal(string(data))
+	}
+
+	code, stdout, stderr = runCommand(t, "", "export", "-format", "jsonl", "-ip-version", "6", filename)
+	if code != 0 {
+		t.Fatal(code, stderr)
+	}
+	if stdout != `{"network":"2a02:ff80::/29","record":{"country":{"geoname_id":2921044,"iso_code":"DE","names":{"en":"Germany"}},"traits":{"is_anycast":true}}}`+"\n" {
+		t.Fatal(stdout)
+	}
+
+	filename = writeTestDatabase(t, "GeoLite2-ASN", map[string]interface{}{
+		"1.0.0.0/24": map[string]interface{}{
+			"autonomous_system_number":       uint32(13335),
+			"autonomous_system_organization": "CLOUDFLARENET",
+		},
+	})
+	code, stdout, stderr = runCommand(t, "", "export", filename)
+	if code != 0 {
+		t.Fatal(code, stderr)
+	}
+	if stdout != "network,autonomous_system_number,autonomous_system_organization\n1.0.0.0/24,13335,CLOUDFLARENET\n" {
+		t.Fatal(stdout)
+	}
+
+	filename = writeTestDatabase(t, "Office-Networks", map[string]interface{}{
+		"10.0.0.0/16": map[string]interface{}{"office": "Berlin"},
+	})
+	code, _, stderr = runCommand(t, "", "export", filename)
+	if code != 1 || !strings.Contains(stderr, "-format jsonl") {
+		t.Fatal(code, stderr)
+	}
+}
+
+func TestImport(t *testing.T) {
+	filename := writeTestDatabase(t, "GeoIP2-City", testCityNetworks)
+	directory := t.TempDir()
+	locations := filepath.Join(directory, "locations.csv")
+	blocks := filepath.Join(directory, "blocks.csv")
+	imported := filepath.Join(directory, "imported.mmdb")
+
+	code, stdout, stderr := runCommand(t, "", "export", "-locations", locations, filename)
+	if code != 0 {
+		t.Fatal(code, stderr)
+	}
+	err := os.WriteFile(blocks, []byte(stdout), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _, stderr = runCommand(t, "", "import", "-type", "GeoIP2-City", "-o", imported, "-locations", locations, blocks)
+	if code != 0 {
+		t.Fatal(code, stderr)
+	}
+
+	original, err := geoip2.NewCityReaderFromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := geoip2.NewCityReaderFromFile(imported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ip := range []string{"81.2.69.142", "2a02:ff80::1"} {
+		expected, err := original.LookupAddr(netip.MustParseAddr(ip))
+		if err != nil {
+			t.Fatal(err)
+		}
+		record, err := reader.LookupAddr(netip.MustParseAddr(ip))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(record, expected) {
+			t.Fatal(ip, record, expected)
+		}
+	}
+
+	code, _, stderr = runCommand(t, "", "import", "-type", "GeoIP2-City", "-o", imported, blocks)
+	if code != 1 || !strings.Contains(stderr, "blocks.csv: line 2: unknown geoname_id: 2643743") {
+		t.Fatal(code, stderr)
+	}
+}
@@ -2,65 +2,69 @@ package geoip2
 
 import (
 	"encoding/binary"
-	"errors"
 	"math"
-	"net"
+	"math/big"
 	"strconv"
 	"unsafe"
 )
 
-func getNetworkString(ip net.IP, mask uint) string {
-	bitLen := 128
-	for i := 0; i < len(ip); i++ {
-		if ip[i] != 0 {
-			if i == 10 {
-				bitLen = 32
-			}
-			break
-		}
-	}
-	cidrMask := net.CIDRMask(int(mask), bitLen)
-	ipNet := net.IPNet{
-		IP:   ip.Mask(cidrMask),
-		Mask: cidrMask,
-	}
-	return ipNet.String()
-}
-
 func readControl(buffer []byte, offset uint) (byte, uint, uint, error) {
+	if offset >= uint(len(buffer)) {
+		return 0, 0, 0, newInvalidDatabaseError(offset, "invalid offset")
+	}
 	controlByte := buffer[offset]
 	offset++
 	dataType := controlByte >> 5
 	if dataType == dataTypeExtended {
+		if offset >= uint(len(buffer)) {
+			return 0, 0, 0, newInvalidDatabaseError(offset, "invalid offset")
+		}
 		dataType = buffer[offset] + 7
 		offset++
 	}
 	size := uint(controlByte & 0x1f)
-	if dataType == dataTypeExtended || size < 29 {
-		return dataType, size, offset, nil
-	}
-	bytesToRead := size - 28
-	newOffset := offset + bytesToRead
-	if newOffset > uint(len(buffer)) {
-		return 0, 0, 0, errors.New("invalid offset")
+	if dataType != dataTypePointer && size >= 29 {
+		bytesToRead := size - 28
+		newOffset := offset + bytesToRead
+		if newOffset > uint(len(buffer)) {
+			return 0, 0, 0, newInvalidDatabaseError(offset, "invalid offset")
+		}
+		size = uint(bytesToUInt64(buffer[offset:newOffset]))
+		switch bytesToRead {
+		case 1:
+			size += 29
+		case 2:
+			size += 285
+		default:
+			size += 65821
+		}
+		offset = newOffset
 	}
-	size = uint(bytesToUInt64(buffer[offset:newOffset]))
-	switch bytesToRead {
-	case 1:
-		size += 29
-	case 2:
-		size += 285
+	available := uint(len(buffer)) - offset
+	switch dataType {
+	case dataTypePointer, dataTypeBool:
+	case dataTypeMap:
+		// Every key and every value take at least one byte.
+		if size > available/2 {
+			return 0, 0, 0, newInvalidDatabaseError(offset, "invalid map size: "+strconv.Itoa(int(size)))
+		}
+	case dataTypeSlice:
+		if size > available {
+			return 0, 0, 0, newInvalidDatabaseError(offset, "invalid slice size: "+strconv.Itoa(int(size)))
+		}
 	default:
-		size += 65821
+		if size > available {
+			return 0, 0, 0, newInvalidDatabaseError(offset, "invalid offset")
+		}
 	}
-	return dataType, size, newOffset, nil
+	return dataType, size, offset, nil
 }
 
 func readPointer(buffer []byte, size uint, offset uint) (uint, uint, error) {
 	pointerSize := ((size >> 3) & 0x3) + 1
 	newOffset := offset + pointerSize
 	if newOffset > uint(len(buffer)) {
-		return 0, 0, errors.New("invalid offset")
+		return 0, 0, newInvalidDatabaseError(offset, "invalid offset")
 	}
 	prefix := uint64(0)
 	if pointerSize != 4 {
@@ -86,6 +90,9 @@ func readFloat64(buffer []byte, offset uint) (float64, uint, error) {
 	}
 	switch dataType {
 	case dataTypeFloat64:
+		if size != 8 {
+			return 0, 0, newInvalidDatabaseError(offset, "invalid float64 size: "+strconv.Itoa(int(size)))
+		}
 		newOffset := offset + size
 		return bytesToFloat64(buffer[offset:newOffset]), newOffset, nil
 	case dataTypePointer:
@@ -98,11 +105,14 @@ func readFloat64(buffer []byte, offset uint) (float64, uint, error) {
 			return 0, 0, err
 		}
 		if dataType != dataTypeFloat64 {
-			return 0, 0, errors.New("invalid float64 pointer type: " + strconv.Itoa(int(dataType)))
+			return 0, 0, newUnexpectedTypeError("", dataTypeFloat64, dataType)
+		}
+		if size != 8 {
+			return 0, 0, newInvalidDatabaseError(offset, "invalid float64 size: "+strconv.Itoa(int(size)))
 		}
 		return bytesToFloat64(buffer[offset : offset+size]), newOffset, nil
 	default:
-		return 0, 0, errors.New("invalid float64 type: " + strconv.Itoa(int(dataType)))
+		return 0, 0, newUnexpectedTypeError("", dataTypeFloat64, dataType)
 	}
 }
 
@@ -113,6 +123,9 @@ func readUInt16(buffer []byte, offset uint) (uint16, uint, error) {
 	}
 	switch dataType {
 	case dataTypeUint16:
+		if size > 2 {
+			return 0, 0, newInvalidDatabaseError(offset, "invalid uint16 size: "+strconv.Itoa(int(size)))
+		}
 		newOffset := offset + size
 		return uint16(bytesToUInt64(buffer[offset:newOffset])), newOffset, nil
 	case dataTypePointer:
@@ -125,11 +138,14 @@ func readUInt16(buffer []byte, offset uint) (uint16, uint, error) {
 			return 0, 0, err
 		}
 		if dataType != dataTypeUint16 {
-			return 0, 0, errors.New("invalid uint16 pointer type: " + strconv.Itoa(int(dataType)))
+			return 0, 0, newUnexpectedTypeError("", dataTypeUint16, dataType)
+		}
+		if size > 2 {
+			return 0, 0, newInvalidDatabaseError(offset, "invalid uint16 size: "+strconv.Itoa(int(size)))
 		}
 		return uint16(bytesToUInt64(buffer[offset : offset+size])), newOffset, nil
 	default:
-		return 0, 0, errors.New("invalid uint16 type: " + strconv.Itoa(int(dataType)))
+		return 0, 0, newUnexpectedTypeError("", dataTypeUint16, dataType)
 	}
 }
 
@@ -140,6 +156,9 @@ func readUInt32(buffer []byte, offset uint) (uint32, uint, error) {
 	}
 	switch dataType {
 	case dataTypeUint32:
+		if size > 4 {
+			return 0, 0, newInvalidDatabaseError(offset, "invalid uint32 size: "+strconv.Itoa(int(size)))
+		}
 		newOffset := offset + size
 		return uint32(bytesToUInt64(buffer[offset:newOffset])), newOffset, nil
 	case dataTypePointer:
@@ -152,11 +171,14 @@ func readUInt32(buffer []byte, offset uint) (uint32, uint, error) {
 			return 0, 0, err
 		}
 		if dataType != dataTypeUint32 {
-			return 0, 0, errors.New("invalid uint32 pointer type: " + strconv.Itoa(int(dataType)))
+			return 0, 0, newUnexpectedTypeError("", dataTypeUint32, dataType)
+		}
+		if size > 4 {
+			return 0, 0, newInvalidDatabaseError(offset, "invalid uint32 size: "+strconv.Itoa(int(size)))
 		}
 		return uint32(bytesToUInt64(buffer[offset : offset+size])), newOffset, nil
 	default:
-		return 0, 0, errors.New("invalid uint32 type: " + strconv.Itoa(int(dataType)))
+		return 0, 0, newUnexpectedTypeError("", dataTypeUint32, dataType)
 	}
 }
 
@@ -178,11 +200,11 @@ func readBool(buffer []byte, offset uint) (bool, uint, error) {
 			return false, 0, err
 		}
 		if dataType != dataTypeBool {
-			return false, 0, errors.New("invalid bool pointer type: " + strconv.Itoa(int(dataType)))
+			return false, 0, newUnexpectedTypeError("", dataTypeBool, dataType)
 		}
 		return size != 0, newOffset, nil
 	default:
-		return false, 0, errors.New("invalid bool type: " + strconv.Itoa(int(dataType)))
+		return false, 0, newUnexpectedTypeError("", dataTypeBool, dataType)
 	}
 }
 
@@ -205,22 +227,181 @@ func readString(buffer []byte, offset uint) (string, uint, error) {
 			return "", 0, err
 		}
 		if dataType != dataTypeString {
-			return "", 0, errors.New("invalid string pointer type: " + strconv.Itoa(int(dataType)))
+			return "", 0, newUnexpectedTypeError("", dataTypeString, dataType)
 		}
 		return b2s(buffer[offset : offset+size]), newOffset, nil
 	default:
-		return "", 0, errors.New("invalid string type: " + strconv.Itoa(int(dataType)))
+		return "", 0, newUnexpectedTypeError("", dataTypeString, dataType)
 	}
 }
 
-func readStringMap(buffer []byte, offset uint) (map[string]string, uint, error) {
+func readInt32(buffer []byte, offset uint) (int32, uint, error) {
+	dataType, size, offset, err := readControl(buffer, offset)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch dataType {
+	case dataTypeInt32:
+		if size > 4 {
+			return 0, 0, newInvalidDatabaseError(offset, "invalid int32 size: "+strconv.Itoa(int(size)))
+		}
+		newOffset := offset + size
+		return int32(bytesToUInt64(buffer[offset:newOffset])), newOffset, nil
+	case dataTypePointer:
+		pointer, newOffset, err := readPointer(buffer, size, offset)
+		if err != nil {
+			return 0, 0, err
+		}
+		dataType, size, offset, err := readControl(buffer, pointer)
+		if err != nil {
+			return 0, 0, err
+		}
+		if dataType != dataTypeInt32 {
+			return 0, 0, newUnexpectedTypeError("", dataTypeInt32, dataType)
+		}
+		if size > 4 {
+			return 0, 0, newInvalidDatabaseError(offset, "invalid int32 size: "+strconv.Itoa(int(size)))
+		}
+		return int32(bytesToUInt64(buffer[offset : offset+size])), newOffset, nil
+	default:
+		return 0, 0, newUnexpectedTypeError("", dataTypeInt32, dataType)
+	}
+}
+
+func readUInt64(buffer []byte, offset uint) (uint64, uint, error) {
+	dataType, size, offset, err := readControl(buffer, offset)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch dataType {
+	case dataTypeUint64:
+		if size > 8 {
+			return 0, 0, newInvalidDatabaseError(offset, "invalid uint64 size: "+strconv.Itoa(int(size)))
+		}
+		newOffset := offset + size
+		return bytesToUInt64(buffer[offset:newOffset]), newOffset, nil
+	case dataTypePointer:
+		pointer, newOffset, err := readPointer(buffer, size, offset)
+		if err != nil {
+			return 0, 0, err
+		}
+		dataType, size, offset, err := readControl(buffer, pointer)
+		if err != nil {
+			return 0, 0, err
+		}
+		if dataType != dataTypeUint64 {
+			return 0, 0, newUnexpectedTypeError("", dataTypeUint64, dataType)
+		}
+		if size > 8 {
+			return 0, 0, newInvalidDatabaseError(offset, "invalid uint64 size: "+strconv.Itoa(int(size)))
+		}
+		return bytesToUInt64(buffer[offset : offset+size]), newOffset, nil
+	default:
+		return 0, 0, newUnexpectedTypeError("", dataTypeUint64, dataType)
+	}
+}
+
+func readUInt128(buffer []byte, offset uint) (*big.Int, uint, error) {
+	dataType, size, offset, err := readControl(buffer, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch dataType {
+	case dataTypeUint128:
+		if size > 16 {
+			return nil, 0, newInvalidDatabaseError(offset, "invalid uint128 size: "+strconv.Itoa(int(size)))
+		}
+		newOffset := offset + size
+		return new(big.Int).SetBytes(buffer[offset:newOffset]), newOffset, nil
+	case dataTypePointer:
+		pointer, newOffset, err := readPointer(buffer, size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		dataType, size, offset, err := readControl(buffer, pointer)
+		if err != nil {
+			return nil, 0, err
+		}
+		if dataType != dataTypeUint128 {
+			return nil, 0, newUnexpectedTypeError("", dataTypeUint128, dataType)
+		}
+		if size > 16 {
+			return nil, 0, newInvalidDatabaseError(offset, "invalid uint128 size: "+strconv.Itoa(int(size)))
+		}
+		return new(big.Int).SetBytes(buffer[offset : offset+size]), newOffset, nil
+	default:
+		return nil, 0, newUnexpectedTypeError("", dataTypeUint128, dataType)
+	}
+}
+
+func readBytes(buffer []byte, offset uint) ([]byte, uint, error) {
+	dataType, size, offset, err := readControl(buffer, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch dataType {
+	case dataTypeBytes:
+		newOffset := offset + size
+		return buffer[offset:newOffset], newOffset, nil
+	case dataTypePointer:
+		pointer, newOffset, err := readPointer(buffer, size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		dataType, size, offset, err := readControl(buffer, pointer)
+		if err != nil {
+			return nil, 0, err
+		}
+		if dataType != dataTypeBytes {
+			return nil, 0, newUnexpectedTypeError("", dataTypeBytes, dataType)
+		}
+		return buffer[offset : offset+size], newOffset, nil
+	default:
+		return nil, 0, newUnexpectedTypeError("", dataTypeBytes, dataType)
+	}
+}
+
+func readFloat32(buffer []byte, offset uint) (float32, uint, error) {
+	dataType, size, offset, err := readControl(buffer, offset)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch dataType {
+	case dataTypeFloat32:
+		if size != 4 {
+			return 0, 0, newInvalidDatabaseError(offset, "invalid float32 size: "+strconv.Itoa(int(size)))
+		}
+		newOffset := offset + size
+		return bytesToFloat32(buffer[offset:newOffset]), newOffset, nil
+	case dataTypePointer:
+		pointer, newOffset, err := readPointer(buffer, size, offset)
+		if err != nil {
+			return 0, 0, err
+		}
+		dataType, size, offset, err := readControl(buffer, pointer)
+		if err != nil {
+			return 0, 0, err
+		}
+		if dataType != dataTypeFloat32 {
+			return 0, 0, newUnexpectedTypeError("", dataTypeFloat32, dataType)
+		}
+		if size != 4 {
+			return 0, 0, newInvalidDatabaseError(offset, "invalid float32 size: "+strconv.Itoa(int(size)))
+		}
+		return bytesToFloat32(buffer[offset : offset+size]), newOffset, nil
+	default:
+		return 0, 0, newUnexpectedTypeError("", dataTypeFloat32, dataType)
+	}
+}
+
+func readStringMap(result map[string]string, buffer []byte, offset uint, languages []string) (map[string]string, uint, error) {
 	dataType, size, offset, err := readControl(buffer, offset)
 	if err != nil {
 		return nil, 0, err
 	}
 	switch dataType {
 	case dataTypeMap:
-		return readStringMapMap(buffer, size, offset)
+		return readStringMapMap(result, buffer, size, offset, languages)
 	case dataTypePointer:
 		pointer, newOffset, err := readPointer(buffer, size, offset)
 		if err != nil {
@@ -231,29 +412,38 @@ func readStringMap(buffer []byte, offset uint) (map[string]string, uint, error)
 			return nil, 0, err
 		}
 		if dataType != dataTypeMap {
-			return nil, 0, errors.New("invalid stringMap pointer type: " + strconv.Itoa(int(dataType)))
+			return nil, 0, newUnexpectedTypeError("", dataTypeMap, dataType)
 		}
-		value, _, err := readStringMapMap(buffer, size, offset)
+		value, _, err := readStringMapMap(result, buffer, size, offset, languages)
 		if err != nil {
 			return nil, 0, err
 		}
 		return value, newOffset, nil
 	default:
-		return nil, 0, errors.New("invalid stringMap type: " + strconv.Itoa(int(dataType)))
+		return nil, 0, newUnexpectedTypeError("", dataTypeMap, dataType)
 	}
 }
 
-func readStringMapMap(buffer []byte, mapSize uint, offset uint) (map[string]string, uint, error) {
+func readStringMapMap(result map[string]string, buffer []byte, mapSize uint, offset uint, languages []string) (map[string]string, uint, error) {
 	var key []byte
 	var err error
 	var dataType byte
 	var size uint
-	result := map[string]string{}
+	if result == nil {
+		result = map[string]string{}
+	}
 	for i := uint(0); i < mapSize; i++ {
 		key, offset, err = readMapKey(buffer, offset)
 		if err != nil {
 			return nil, 0, err
 		}
+		if languages != nil && !containsKey(languages, key) {
+			offset, err = skipValue(buffer, offset, 0)
+			if err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
 		dataType, size, offset, err = readControl(buffer, offset)
 		if err != nil {
 			return nil, 0, err
@@ -269,7 +459,7 @@ func readStringMapMap(buffer []byte, mapSize uint, offset uint) (map[string]stri
 				return nil, 0, err
 			}
 			if dataType != dataTypeString {
-				return nil, 0, errors.New("map key must be a string, got: " + strconv.Itoa(int(dataType)))
+				return nil, 0, newUnexpectedTypeError(string(key), dataTypeString, dataType)
 			}
 			offset = newOffset
 			result[b2s(key)] = b2s(buffer[valueOffset : valueOffset+size])
@@ -279,12 +469,27 @@ func readStringMapMap(buffer []byte, mapSize uint, offset uint) (map[string]stri
 			offset = newOffset
 			result[b2s(key)] = value
 		default:
-			return nil, 0, errors.New("invalid data type of key " + string(key) + ": " + strconv.Itoa(int(dataType)))
+			return nil, 0, newUnexpectedTypeError(string(key), dataTypeString, dataType)
 		}
 	}
 	return result, offset, nil
 }
 
+func containsKey(keys []string, key []byte) bool {
+	for _, value := range keys {
+		if value == b2s(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func clearStringMap(value map[string]string) {
+	for key := range value {
+		delete(value, key)
+	}
+}
+
 func readMapKey(buffer []byte, offset uint) ([]byte, uint, error) {
 	dataType, size, offset, err := readControl(buffer, offset)
 	if err != nil {
@@ -300,16 +505,16 @@ func readMapKey(buffer []byte, offset uint) ([]byte, uint, error) {
 			return nil, 0, err
 		}
 		if dataType != dataTypeString {
-			return nil, 0, errors.New("map key must be a string, got: " + strconv.Itoa(int(dataType)))
+			return nil, 0, newUnexpectedTypeError("map key", dataTypeString, dataType)
 		}
 		return buffer[offset : offset+size], newOffset, nil
 	}
 	if dataType != dataTypeString {
-		return nil, 0, errors.New("map key must be a string, got: " + strconv.Itoa(int(dataType)))
+		return nil, 0, newUnexpectedTypeError("map key", dataTypeString, dataType)
 	}
 	newOffset := offset + size
 	if newOffset > uint(len(buffer)) {
-		return nil, 0, errors.New("invalid offset")
+		return nil, 0, newInvalidDatabaseError(offset, "invalid offset")
 	}
 	return buffer[offset:newOffset], newOffset, nil
 }
@@ -387,3 +592,45 @@ func bytesToFloat64(buffer []byte) float64 {
 func b2s(value []byte) string {
 	return *(*string)(unsafe.Pointer(&value))
 }
+
+func skipValue(buffer []byte, offset uint, depth uint) (uint, error) {
+	if depth > maxDataDepth {
+		return 0, newInvalidDatabaseError(offset, "maximum data structure depth exceeded")
+	}
+	dataType, size, offset, err := readControl(buffer, offset)
+	if err != nil {
+		return 0, err
+	}
+	switch dataType {
+	case dataTypePointer:
+		_, newOffset, err := readPointer(buffer, size, offset)
+		if err != nil {
+			return 0, err
+		}
+		return newOffset, nil
+	case dataTypeMap:
+		for i := uint(0); i < size*2; i++ {
+			offset, err = skipValue(buffer, offset, depth+1)
+			if err != nil {
+				return 0, err
+			}
+		}
+		return offset, nil
+	case dataTypeSlice:
+		for i := uint(0); i < size; i++ {
+			offset, err = skipValue(buffer, offset, depth+1)
+			if err != nil {
+				return 0, err
+			}
+		}
+		return offset, nil
+	case dataTypeBool:
+		return offset, nil
+	default:
+		newOffset := offset + size
+		if newOffset > uint(len(buffer)) {
+			return 0, newInvalidDatabaseError(offset, "invalid offset")
+		}
+		return newOffset, nil
+	}
+}
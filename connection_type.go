@@ -1,8 +1,6 @@
 package geoip2
 
-import "errors"
-
-func readConnectionTypeMap(result *ConnectionType, buffer []byte, mapSize uint, offset uint) (uint, error) {
+func readConnectionTypeMap(result *ConnectionType, buffer []byte, mapSize uint, offset uint, options *options) (uint, error) {
 	var key []byte
 	var err error
 	for i := uint(0); i < mapSize; i++ {
@@ -14,10 +12,13 @@ func readConnectionTypeMap(result *ConnectionType, buffer []byte, mapSize uint,
 		case "connection_type":
 			result.ConnectionType, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "", key)
 			}
 		default:
-			return 0, errors.New("unknown connectionType key: " + string(key))
+			offset, err = skipUnknownKey(options, "", key, buffer, offset)
+			if err != nil {
+				return 0, err
+			}
 		}
 	}
 	return offset, nil
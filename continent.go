@@ -1,18 +1,13 @@
 package geoip2
 
-import (
-	"errors"
-	"strconv"
-)
-
-func readContinent(continent *Continent, buffer []byte, offset uint) (uint, error) {
+func readContinent(continent *Continent, buffer []byte, offset uint, options *options) (uint, error) {
 	dataType, size, offset, err := readControl(buffer, offset)
 	if err != nil {
 		return 0, err
 	}
 	switch dataType {
 	case dataTypeMap:
-		return readContinentMap(continent, buffer, size, offset)
+		return readContinentMap(continent, buffer, size, offset, options)
 	case dataTypePointer:
 		pointer, newOffset, err := readPointer(buffer, size, offset)
 		if err != nil {
@@ -23,19 +18,19 @@ func readContinent(continent *Continent, buffer []byte, offset uint) (uint, erro
 			return 0, err
 		}
 		if dataType != dataTypeMap {
-			return 0, errors.New("invalid continent pointer type: " + strconv.Itoa(int(dataType)))
+			return 0, newUnexpectedTypeError("continent", dataTypeMap, dataType)
 		}
-		_, err = readContinentMap(continent, buffer, size, offset)
+		_, err = readContinentMap(continent, buffer, size, offset, options)
 		if err != nil {
 			return 0, err
 		}
 		return newOffset, nil
 	default:
-		return 0, errors.New("invalid continent type: " + strconv.Itoa(int(dataType)))
+		return 0, newUnexpectedTypeError("continent", dataTypeMap, dataType)
 	}
 }
 
-func readContinentMap(continent *Continent, buffer []byte, mapSize uint, offset uint) (uint, error) {
+func readContinentMap(continent *Continent, buffer []byte, mapSize uint, offset uint, options *options) (uint, error) {
 	var key []byte
 	var err error
 	for i := uint(0); i < mapSize; i++ {
@@ -47,21 +42,32 @@ func readContinentMap(continent *Continent, buffer []byte, mapSize uint, offset
 		case "geoname_id":
 			continent.GeoNameID, offset, err = readUInt32(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "continent", key)
 			}
 		case "code":
 			continent.Code, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "continent", key)
 			}
 		case "names":
-			continent.Names, offset, err = readStringMap(buffer, offset)
+			continent.Names, offset, err = readStringMap(continent.Names, buffer, offset, options.languages)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "continent", key)
 			}
 		default:
-			return 0, errors.New("unknown continent key: " + string(key))
+			offset, err = skipUnknownKey(options, "continent", key, buffer, offset)
+			if err != nil {
+				return 0, err
+			}
 		}
 	}
 	return offset, nil
 }
+
+func (continent *Continent) reset() {
+	names := continent.Names
+	clearStringMap(names)
+	*continent = Continent{
+		Names: names,
+	}
+}
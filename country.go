@@ -1,18 +1,13 @@
 package geoip2
 
-import (
-	"errors"
-	"strconv"
-)
-
-func readCountry(country *Country, buffer []byte, offset uint) (uint, error) {
+func readCountry(country *Country, buffer []byte, offset uint, options *options) (uint, error) {
 	dataType, size, offset, err := readControl(buffer, offset)
 	if err != nil {
 		return 0, err
 	}
 	switch dataType {
 	case dataTypeMap:
-		return readCountryMap(country, buffer, size, offset)
+		return readCountryMap(country, buffer, size, offset, options)
 	case dataTypePointer:
 		pointer, newOffset, err := readPointer(buffer, size, offset)
 		if err != nil {
@@ -23,19 +18,19 @@ func readCountry(country *Country, buffer []byte, offset uint) (uint, error) {
 			return 0, err
 		}
 		if dataType != dataTypeMap {
-			return 0, errors.New("invalid country pointer type: " + strconv.Itoa(int(dataType)))
+			return 0, newUnexpectedTypeError("country", dataTypeMap, dataType)
 		}
-		_, err = readCountryMap(country, buffer, size, offset)
+		_, err = readCountryMap(country, buffer, size, offset, options)
 		if err != nil {
 			return 0, err
 		}
 		return newOffset, nil
 	default:
-		return 0, errors.New("invalid country type: " + strconv.Itoa(int(dataType)))
+		return 0, newUnexpectedTypeError("country", dataTypeMap, dataType)
 	}
 }
 
-func readCountryMap(country *Country, buffer []byte, mapSize uint, offset uint) (uint, error) {
+func readCountryMap(country *Country, buffer []byte, mapSize uint, offset uint, options *options) (uint, error) {
 	var key []byte
 	var err error
 	for i := uint(0); i < mapSize; i++ {
@@ -47,36 +42,47 @@ func readCountryMap(country *Country, buffer []byte, mapSize uint, offset uint)
 		case "geoname_id":
 			country.GeoNameID, offset, err = readUInt32(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "country", key)
 			}
 		case "iso_code":
 			country.ISOCode, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "country", key)
 			}
 		case "names":
-			country.Names, offset, err = readStringMap(buffer, offset)
+			country.Names, offset, err = readStringMap(country.Names, buffer, offset, options.languages)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "country", key)
 			}
 		case "is_in_european_union":
 			country.IsInEuropeanUnion, offset, err = readBool(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "country", key)
 			}
 		case "type":
 			country.Type, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "country", key)
 			}
 		case "confidence":
 			country.Confidence, offset, err = readUInt16(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "country", key)
 			}
 		default:
-			return 0, errors.New("unknown country key: " + string(key))
+			offset, err = skipUnknownKey(options, "country", key, buffer, offset)
+			if err != nil {
+				return 0, err
+			}
 		}
 	}
 	return offset, nil
 }
+
+func (country *Country) reset() {
+	names := country.Names
+	clearStringMap(names)
+	*country = Country{
+		Names: names,
+	}
+}
@@ -0,0 +1,437 @@
+package geoip2
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+var (
+	structFieldsCache sync.Map // map[reflect.Type]map[string][]int
+	bigIntType        = reflect.TypeOf(big.Int{})
+)
+
+func getStructFields(structType reflect.Type) map[string][]int {
+	fields, ok := structFieldsCache.Load(structType)
+	if ok {
+		return fields.(map[string][]int)
+	}
+	result := map[string][]int{}
+	collectStructFields(structType, nil, result)
+	structFieldsCache.Store(structType, result)
+	return result
+}
+
+func collectStructFields(structType reflect.Type, index []int, fields map[string][]int) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get("maxminddb")
+		if tag == "-" {
+			continue
+		}
+		fieldIndex := make([]int, len(index)+1)
+		copy(fieldIndex, index)
+		fieldIndex[len(index)] = i
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			collectStructFields(field.Type, fieldIndex, fields)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		name := tag
+		if name == "" {
+			name = field.Name
+		}
+		if existing, ok := fields[name]; ok && len(existing) <= len(fieldIndex) {
+			continue
+		}
+		fields[name] = fieldIndex
+	}
+}
+
+// dataBudget bounds the number of values decoded from a record. Pointers let
+// values share data, so a malformed data section of a few bytes could expand
+// exponentially. A record decodes at most as many values as the data section
+// has bytes, and at least minDataBudgetSize.
+type dataBudget struct {
+	values uint
+}
+
+func newDataBudget(buffer []byte) *dataBudget {
+	values := uint(len(buffer))
+	if values < minDataBudgetSize {
+		values = minDataBudgetSize
+	}
+	return &dataBudget{
+		values: values,
+	}
+}
+
+func (b *dataBudget) spend(offset uint) error {
+	if b.values == 0 {
+		return newInvalidDatabaseError(offset, "maximum number of decoded values exceeded")
+	}
+	b.values--
+	return nil
+}
+
+func decodeReflectInto(buffer []byte, offset uint, result interface{}) error {
+	value := reflect.ValueOf(result)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return errors.New("result must be a non-nil pointer")
+	}
+	_, err := decodeReflect(buffer, offset, value.Elem(), 0, newDataBudget(buffer))
+	return err
+}
+
+func decodeReflect(buffer []byte, offset uint, value reflect.Value, depth uint, budget *dataBudget) (uint, error) {
+	if depth > maxDataDepth {
+		return 0, newInvalidDatabaseError(offset, "maximum data structure depth exceeded")
+	}
+	err := budget.spend(offset)
+	if err != nil {
+		return 0, err
+	}
+	dataType, size, dataOffset, err := readControl(buffer, offset)
+	if err != nil {
+		return 0, err
+	}
+	if dataType == dataTypePointer {
+		pointer, newOffset, err := readPointer(buffer, size, dataOffset)
+		if err != nil {
+			return 0, err
+		}
+		dataType, _, _, err = readControl(buffer, pointer)
+		if err != nil {
+			return 0, err
+		}
+		if dataType == dataTypePointer {
+			return 0, newInvalidDatabaseError(pointer, "invalid pointer to pointer")
+		}
+		_, err = decodeReflect(buffer, pointer, value, depth+1, budget)
+		if err != nil {
+			return 0, err
+		}
+		return newOffset, nil
+	}
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Interface && value.NumMethod() == 0 {
+		result, newOffset, err := readInterface(buffer, offset, depth, budget)
+		if err != nil {
+			return 0, err
+		}
+		value.Set(reflect.ValueOf(result))
+		return newOffset, nil
+	}
+	switch dataType {
+	case dataTypeMap:
+		return decodeReflectMap(buffer, size, dataOffset, value, depth, budget)
+	case dataTypeSlice:
+		return decodeReflectSlice(buffer, size, dataOffset, value, depth, budget)
+	case dataTypeString:
+		if value.Kind() != reflect.String {
+			return 0, newDecodeTypeError(value.Type(), dataType)
+		}
+		result, newOffset, err := readString(buffer, offset)
+		if err != nil {
+			return 0, err
+		}
+		value.SetString(result)
+		return newOffset, nil
+	case dataTypeBytes:
+		if value.Kind() != reflect.Slice || value.Type().Elem().Kind() != reflect.Uint8 {
+			return 0, newDecodeTypeError(value.Type(), dataType)
+		}
+		result, newOffset, err := readBytes(buffer, offset)
+		if err != nil {
+			return 0, err
+		}
+		value.SetBytes(append([]byte(nil), result...))
+		return newOffset, nil
+	case dataTypeFloat64:
+		result, newOffset, err := readFloat64(buffer, offset)
+		if err != nil {
+			return 0, err
+		}
+		return newOffset, setReflectFloat(value, dataType, result)
+	case dataTypeFloat32:
+		result, newOffset, err := readFloat32(buffer, offset)
+		if err != nil {
+			return 0, err
+		}
+		return newOffset, setReflectFloat(value, dataType, float64(result))
+	case dataTypeUint16:
+		result, newOffset, err := readUInt16(buffer, offset)
+		if err != nil {
+			return 0, err
+		}
+		return newOffset, setReflectUint(value, dataType, uint64(result))
+	case dataTypeUint32:
+		result, newOffset, err := readUInt32(buffer, offset)
+		if err != nil {
+			return 0, err
+		}
+		return newOffset, setReflectUint(value, dataType, uint64(result))
+	case dataTypeUint64:
+		result, newOffset, err := readUInt64(buffer, offset)
+		if err != nil {
+			return 0, err
+		}
+		return newOffset, setReflectUint(value, dataType, result)
+	case dataTypeInt32:
+		result, newOffset, err := readInt32(buffer, offset)
+		if err != nil {
+			return 0, err
+		}
+		return newOffset, setReflectInt(value, dataType, int64(result))
+	case dataTypeUint128:
+		result, newOffset, err := readUInt128(buffer, offset)
+		if err != nil {
+			return 0, err
+		}
+		if value.Type() == bigIntType {
+			value.Addr().Interface().(*big.Int).Set(result)
+			return newOffset, nil
+		}
+		if !result.IsUint64() {
+			return 0, newOverflowError(value.Type(), result.String())
+		}
+		return newOffset, setReflectUint(value, dataType, result.Uint64())
+	case dataTypeBool:
+		if value.Kind() != reflect.Bool {
+			return 0, newDecodeTypeError(value.Type(), dataType)
+		}
+		result, newOffset, err := readBool(buffer, offset)
+		if err != nil {
+			return 0, err
+		}
+		value.SetBool(result)
+		return newOffset, nil
+	default:
+		return 0, newInvalidDatabaseError(offset, "invalid data type: "+strconv.Itoa(int(dataType)))
+	}
+}
+
+func setReflectFloat(value reflect.Value, dataType byte, v float64) error {
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		value.SetFloat(v)
+		return nil
+	default:
+		return newDecodeTypeError(value.Type(), dataType)
+	}
+}
+
+func setReflectUint(value reflect.Value, dataType byte, v uint64) error {
+	switch value.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if value.OverflowUint(v) {
+			return newOverflowError(value.Type(), strconv.FormatUint(v, 10))
+		}
+		value.SetUint(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v > math.MaxInt64 || value.OverflowInt(int64(v)) {
+			return newOverflowError(value.Type(), strconv.FormatUint(v, 10))
+		}
+		value.SetInt(int64(v))
+	default:
+		return newDecodeTypeError(value.Type(), dataType)
+	}
+	return nil
+}
+
+func setReflectInt(value reflect.Value, dataType byte, v int64) error {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.OverflowInt(v) {
+			return newOverflowError(value.Type(), strconv.FormatInt(v, 10))
+		}
+		value.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v < 0 || value.OverflowUint(uint64(v)) {
+			return newOverflowError(value.Type(), strconv.FormatInt(v, 10))
+		}
+		value.SetUint(uint64(v))
+	default:
+		return newDecodeTypeError(value.Type(), dataType)
+	}
+	return nil
+}
+
+func decodeReflectMap(buffer []byte, mapSize uint, offset uint, value reflect.Value, depth uint, budget *dataBudget) (uint, error) {
+	var key []byte
+	var err error
+	switch value.Kind() {
+	case reflect.Struct:
+		fields := getStructFields(value.Type())
+		for i := uint(0); i < mapSize; i++ {
+			key, offset, err = readMapKey(buffer, offset)
+			if err != nil {
+				return 0, err
+			}
+			index, ok := fields[b2s(key)]
+			if !ok {
+				offset, err = skipValue(buffer, offset, depth+1)
+			} else {
+				offset, err = decodeReflect(buffer, offset, value.FieldByIndex(index), depth+1, budget)
+			}
+			if err != nil {
+				return 0, withField(err, "", key)
+			}
+		}
+		return offset, nil
+	case reflect.Map:
+		mapType := value.Type()
+		if mapType.Key().Kind() != reflect.String {
+			return 0, newDecodeTypeError(mapType, dataTypeMap)
+		}
+		if value.IsNil() {
+			value.Set(reflect.MakeMapWithSize(mapType, int(mapSize)))
+		}
+		for i := uint(0); i < mapSize; i++ {
+			key, offset, err = readMapKey(buffer, offset)
+			if err != nil {
+				return 0, err
+			}
+			elem := reflect.New(mapType.Elem()).Elem()
+			offset, err = decodeReflect(buffer, offset, elem, depth+1, budget)
+			if err != nil {
+				return 0, withField(err, "", key)
+			}
+			value.SetMapIndex(reflect.ValueOf(b2s(key)).Convert(mapType.Key()), elem)
+		}
+		return offset, nil
+	default:
+		return 0, newDecodeTypeError(value.Type(), dataTypeMap)
+	}
+}
+
+func decodeReflectSlice(buffer []byte, sliceSize uint, offset uint, value reflect.Value, depth uint, budget *dataBudget) (uint, error) {
+	var err error
+	switch value.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), int(sliceSize), int(sliceSize))
+		for i := uint(0); i < sliceSize; i++ {
+			offset, err = decodeReflect(buffer, offset, slice.Index(int(i)), depth+1, budget)
+			if err != nil {
+				return 0, err
+			}
+		}
+		value.Set(slice)
+		return offset, nil
+	case reflect.Array:
+		for i := uint(0); i < sliceSize; i++ {
+			if i < uint(value.Len()) {
+				offset, err = decodeReflect(buffer, offset, value.Index(int(i)), depth+1, budget)
+			} else {
+				offset, err = skipValue(buffer, offset, depth+1)
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+		return offset, nil
+	default:
+		return 0, newDecodeTypeError(value.Type(), dataTypeSlice)
+	}
+}
+
+func readInterface(buffer []byte, offset uint, depth uint, budget *dataBudget) (interface{}, uint, error) {
+	if depth > maxDataDepth {
+		return nil, 0, newInvalidDatabaseError(offset, "maximum data structure depth exceeded")
+	}
+	err := budget.spend(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	dataType, size, dataOffset, err := readControl(buffer, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch dataType {
+	case dataTypePointer:
+		pointer, newOffset, err := readPointer(buffer, size, dataOffset)
+		if err != nil {
+			return nil, 0, err
+		}
+		dataType, _, _, err = readControl(buffer, pointer)
+		if err != nil {
+			return nil, 0, err
+		}
+		if dataType == dataTypePointer {
+			return nil, 0, newInvalidDatabaseError(pointer, "invalid pointer to pointer")
+		}
+		value, _, err := readInterface(buffer, pointer, depth+1, budget)
+		if err != nil {
+			return nil, 0, err
+		}
+		return value, newOffset, nil
+	case dataTypeMap:
+		var key []byte
+		offset = dataOffset
+		result := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, offset, err = readMapKey(buffer, offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			result[b2s(key)], offset, err = readInterface(buffer, offset, depth+1, budget)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		return result, offset, nil
+	case dataTypeSlice:
+		offset = dataOffset
+		result := make([]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			result[i], offset, err = readInterface(buffer, offset, depth+1, budget)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		return result, offset, nil
+	case dataTypeString:
+		return toInterface(readString(buffer, offset))
+	case dataTypeFloat64:
+		return toInterface(readFloat64(buffer, offset))
+	case dataTypeBytes:
+		result, newOffset, err := readBytes(buffer, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		return append([]byte(nil), result...), newOffset, nil
+	case dataTypeUint16:
+		return toInterface(readUInt16(buffer, offset))
+	case dataTypeUint32:
+		return toInterface(readUInt32(buffer, offset))
+	case dataTypeInt32:
+		return toInterface(readInt32(buffer, offset))
+	case dataTypeUint64:
+		return toInterface(readUInt64(buffer, offset))
+	case dataTypeUint128:
+		return toInterface(readUInt128(buffer, offset))
+	case dataTypeBool:
+		return toInterface(readBool(buffer, offset))
+	case dataTypeFloat32:
+		return toInterface(readFloat32(buffer, offset))
+	default:
+		return nil, 0, newInvalidDatabaseError(offset, "invalid data type: "+strconv.Itoa(int(dataType)))
+	}
+}
+
+func toInterface[T any](value T, offset uint, err error) (interface{}, uint, error) {
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, offset, nil
+}
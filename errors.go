@@ -0,0 +1,188 @@
+package geoip2
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrIPv6InIPv4DB  = errors.New("cannot look up an IPv6 address in an IPv4-only database")
+	ErrReaderClosed  = errors.New("reader is closed")
+	ErrInvalidIP     = errors.New("invalid IP")
+	ErrInvalidPrefix = errors.New("invalid prefix")
+)
+
+// InvalidDatabaseError is returned when the database is corrupt or truncated.
+// Offset is relative to the section the error was found in: the search tree,
+// the data section or the metadata.
+type InvalidDatabaseError struct {
+	Offset  uint
+	Message string
+}
+
+func (e *InvalidDatabaseError) Error() string {
+	return "invalid MaxMind DB at offset " + strconv.FormatUint(uint64(e.Offset), 10) + ": " + e.Message
+}
+
+// UnexpectedTypeError is returned when a value of the database does not have
+// the type expected for Field, either by the reader or by the Go type a value
+// is decoded into.
+type UnexpectedTypeError struct {
+	Field    string
+	Expected string
+	Got      string
+}
+
+func (e *UnexpectedTypeError) Error() string {
+	if e.Field == "" {
+		return "unexpected type: expected " + e.Expected + ", got " + e.Got
+	}
+	return "unexpected " + e.Field + " type: expected " + e.Expected + ", got " + e.Got
+}
+
+// OverflowError is returned when a value of the database does not fit the Go
+// type of Field it is decoded into, e.g. a uint32 of 70000 into a uint16.
+type OverflowError struct {
+	Field string
+	Type  string
+	Value string
+}
+
+func (e *OverflowError) Error() string {
+	if e.Field == "" {
+		return "value " + e.Value + " overflows " + e.Type
+	}
+	return e.Field + " value " + e.Value + " overflows " + e.Type
+}
+
+// WrongDatabaseTypeError is returned by the New*Reader constructors when the
+// database type does not match the reader.
+type WrongDatabaseTypeError struct {
+	Want string
+	Got  string
+}
+
+func (e *WrongDatabaseTypeError) Error() string {
+	return "wrong MaxMind DB " + e.Want + " type: " + e.Got
+}
+
+// UnknownFieldError is returned by the City and Country reader constructors
+// when a key passed to WithFields is not a top-level key of their records.
+type UnknownFieldError struct {
+	Field string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return "unknown field: " + e.Field
+}
+
+// UnknownLanguageError is returned by the reader constructors when a locale
+// passed to WithLanguages is not in the Languages of the database metadata.
+type UnknownLanguageError struct {
+	Language string
+}
+
+func (e *UnknownLanguageError) Error() string {
+	return "the MaxMind DB does not contain language: " + e.Language
+}
+
+func newInvalidDatabaseError(offset uint, message string) error {
+	return &InvalidDatabaseError{
+		Offset:  offset,
+		Message: message,
+	}
+}
+
+func newUnexpectedTypeError(field string, expected byte, got byte) error {
+	return &UnexpectedTypeError{
+		Field:    field,
+		Expected: dataTypeName(expected),
+		Got:      dataTypeName(got),
+	}
+}
+
+// withField adds the path of the value of key in section to the field of an
+// UnexpectedTypeError or OverflowError, as the readers of values do not know
+// their key.
+func withField(err error, section string, key []byte) error {
+	switch err := err.(type) {
+	case *UnexpectedTypeError:
+		err.Field = fieldPath(section, key, err.Field)
+	case *OverflowError:
+		err.Field = fieldPath(section, key, err.Field)
+	}
+	return err
+}
+
+func fieldPath(section string, key []byte, field string) string {
+	path := string(key)
+	if field != "" {
+		path += "." + field
+	}
+	if section != "" {
+		path = section + "." + path
+	}
+	return path
+}
+
+func newDecodeTypeError(expected reflect.Type, got byte) error {
+	return &UnexpectedTypeError{
+		Expected: expected.String(),
+		Got:      dataTypeName(got),
+	}
+}
+
+func newOverflowError(valueType reflect.Type, value string) error {
+	return &OverflowError{
+		Type:  valueType.String(),
+		Value: value,
+	}
+}
+
+// newWrongDatabaseTypeError copies the database types, which may point into a
+// memory-mapped file that is unmapped once the error is returned.
+func newWrongDatabaseTypeError(want string, got string) error {
+	return &WrongDatabaseTypeError{
+		Want: string([]byte(want)),
+		Got:  string([]byte(got)),
+	}
+}
+
+func dataTypeName(dataType byte) string {
+	switch dataType {
+	case dataTypePointer:
+		return "pointer"
+	case dataTypeString:
+		return "string"
+	case dataTypeFloat64:
+		return "float64"
+	case dataTypeBytes:
+		return "bytes"
+	case dataTypeUint16:
+		return "uint16"
+	case dataTypeUint32:
+		return "uint32"
+	case dataTypeMap:
+		return "map"
+	case dataTypeInt32:
+		return "int32"
+	case dataTypeUint64:
+		return "uint64"
+	case dataTypeUint128:
+		return "uint128"
+	case dataTypeSlice:
+		return "slice"
+	case dataTypeDataCacheContainer:
+		return "data cache container"
+	case dataTypeEndMarker:
+		return "end marker"
+	case dataTypeBool:
+		return "bool"
+	case dataTypeFloat32:
+		return "float32"
+	default:
+		return "unknown type " + strconv.Itoa(int(dataType))
+	}
+}
@@ -1,8 +1,6 @@
 package geoip2
 
-import "errors"
-
-func readISPMap(result *ISP, buffer []byte, mapSize uint, offset uint) (uint, error) {
+func readISPMap(result *ISP, buffer []byte, mapSize uint, offset uint, options *options) (uint, error) {
 	var key []byte
 	var err error
 	for i := uint(0); i < mapSize; i++ {
@@ -14,35 +12,38 @@ func readISPMap(result *ISP, buffer []byte, mapSize uint, offset uint) (uint, er
 		case "autonomous_system_number":
 			result.AutonomousSystemNumber, offset, err = readUInt32(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "", key)
 			}
 		case "autonomous_system_organization":
 			result.AutonomousSystemOrganization, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "", key)
 			}
 		case "isp":
 			result.ISP, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "", key)
 			}
 		case "organization":
 			result.Organization, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "", key)
 			}
 		case "mobile_country_code":
 			result.MobileCountryCode, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "", key)
 			}
 		case "mobile_network_code":
 			result.MobileNetworkCode, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "", key)
 			}
 		default:
-			return 0, errors.New("unknown isp key: " + string(key))
+			offset, err = skipUnknownKey(options, "", key, buffer, offset)
+			if err != nil {
+				return 0, err
+			}
 		}
 	}
 	return offset, nil
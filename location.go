@@ -1,18 +1,13 @@
 package geoip2
 
-import (
-	"errors"
-	"strconv"
-)
-
-func readLocation(location *Location, buffer []byte, offset uint) (uint, error) {
+func readLocation(location *Location, buffer []byte, offset uint, options *options) (uint, error) {
 	dataType, size, offset, err := readControl(buffer, offset)
 	if err != nil {
 		return 0, err
 	}
 	switch dataType {
 	case dataTypeMap:
-		return readLocationMap(location, buffer, size, offset)
+		return readLocationMap(location, buffer, size, offset, options)
 	case dataTypePointer:
 		pointer, newOffset, err := readPointer(buffer, size, offset)
 		if err != nil {
@@ -23,19 +18,19 @@ func readLocation(location *Location, buffer []byte, offset uint) (uint, error)
 			return 0, err
 		}
 		if dataType != dataTypeMap {
-			return 0, errors.New("invalid location pointer type: " + strconv.Itoa(int(dataType)))
+			return 0, newUnexpectedTypeError("location", dataTypeMap, dataType)
 		}
-		_, err = readLocationMap(location, buffer, size, offset)
+		_, err = readLocationMap(location, buffer, size, offset, options)
 		if err != nil {
 			return 0, err
 		}
 		return newOffset, nil
 	default:
-		return 0, errors.New("invalid location type: " + strconv.Itoa(int(dataType)))
+		return 0, newUnexpectedTypeError("location", dataTypeMap, dataType)
 	}
 }
 
-func readLocationMap(location *Location, buffer []byte, mapSize uint, offset uint) (uint, error) {
+func readLocationMap(location *Location, buffer []byte, mapSize uint, offset uint, options *options) (uint, error) {
 	var key []byte
 	var err error
 	for i := uint(0); i < mapSize; i++ {
@@ -47,30 +42,33 @@ func readLocationMap(location *Location, buffer []byte, mapSize uint, offset uin
 		case "latitude":
 			location.Latitude, offset, err = readFloat64(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "location", key)
 			}
 		case "longitude":
 			location.Longitude, offset, err = readFloat64(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "location", key)
 			}
 		case "accuracy_radius":
 			location.AccuracyRadius, offset, err = readUInt16(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "location", key)
 			}
 		case "time_zone":
 			location.TimeZone, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "location", key)
 			}
 		case "metro_code":
 			location.MetroCode, offset, err = readUInt16(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "location", key)
 			}
 		default:
-			return 0, errors.New("unknown location key: " + string(key))
+			offset, err = skipUnknownKey(options, "location", key, buffer, offset)
+			if err != nil {
+				return 0, err
+			}
 		}
 	}
 	return offset, nil
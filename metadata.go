@@ -1,9 +1,6 @@
 package geoip2
 
-import (
-	"errors"
-	"strconv"
-)
+import "time"
 
 type Metadata struct {
 	NodeCount                uint32            // node_count This is an unsigned 32-bit integer indicating the number of nodes in the search tree.
@@ -17,15 +14,25 @@ type Metadata struct {
 	Description              map[string]string // description This key will always point to a map. The keys of that map will be language codes, and the values will be a description in that language as a UTF-8 string. The codes may include additional information such as script or country identifiers, like “zh-TW” or “mn-Cyrl-MN”. The additional identifiers will be separated by a dash character (“-“).
 }
 
+// BuildTime returns the time the database was built.
+func (m *Metadata) BuildTime() time.Time {
+	return time.Unix(int64(m.BuildEpoch), 0).UTC()
+}
+
+// Age returns the time elapsed since the database was built.
+func (m *Metadata) Age() time.Duration {
+	return time.Since(m.BuildTime())
+}
+
 var metadataStartMarker = []byte("\xAB\xCD\xEFMaxMind.com")
 
-func readMetadata(buffer []byte) (*Metadata, error) {
+func readMetadata(buffer []byte, options *options) (*Metadata, error) {
 	dataType, metadataSize, offset, err := readControl(buffer, 0)
 	if err != nil {
 		return nil, err
 	}
 	if dataType != dataTypeMap {
-		return nil, errors.New("invalid metadata type: " + strconv.Itoa(int(dataType)))
+		return nil, newUnexpectedTypeError("metadata", dataTypeMap, dataType)
 	}
 	var key []byte
 	metadata := &Metadata{}
@@ -35,6 +42,7 @@ func readMetadata(buffer []byte) (*Metadata, error) {
 			return nil, err
 		}
 		size := uint(0)
+		valueOffset := offset
 		dataType, size, offset, err = readControl(buffer, offset)
 		if err != nil {
 			return nil, err
@@ -43,45 +51,45 @@ func readMetadata(buffer []byte) (*Metadata, error) {
 		switch b2s(key) {
 		case "binary_format_major_version":
 			if dataType != dataTypeUint16 {
-				return nil, errors.New("invalid binary_format_major_version type: " + strconv.Itoa(int(dataType)))
+				return nil, newUnexpectedTypeError("binary_format_major_version", dataTypeUint16, dataType)
 			}
 			newOffset = offset + size
 			metadata.BinaryFormatMajorVersion = uint16(bytesToUInt64(buffer[offset:newOffset]))
 		case "binary_format_minor_version":
 			if dataType != dataTypeUint16 {
-				return nil, errors.New("invalid binary_format_minor_version type: " + strconv.Itoa(int(dataType)))
+				return nil, newUnexpectedTypeError("binary_format_minor_version", dataTypeUint16, dataType)
 			}
 			newOffset = offset + size
 			metadata.BinaryFormatMinorVersion = uint16(bytesToUInt64(buffer[offset:newOffset]))
 		case "build_epoch":
 			if dataType != dataTypeUint64 {
-				return nil, errors.New("invalid build_epoch type: " + strconv.Itoa(int(dataType)))
+				return nil, newUnexpectedTypeError("build_epoch", dataTypeUint64, dataType)
 			}
 			newOffset = offset + size
 			metadata.BuildEpoch = bytesToUInt64(buffer[offset:newOffset])
 		case "database_type":
 			if dataType != dataTypeString {
-				return nil, errors.New("invalid database_type type: " + strconv.Itoa(int(dataType)))
+				return nil, newUnexpectedTypeError("database_type", dataTypeString, dataType)
 			}
 			newOffset = offset + size
 			metadata.DatabaseType = b2s(buffer[offset:newOffset])
 		case "description":
 			if dataType != dataTypeMap {
-				return nil, errors.New("invalid description type: " + strconv.Itoa(int(dataType)))
+				return nil, newUnexpectedTypeError("description", dataTypeMap, dataType)
 			}
-			metadata.Description, newOffset, err = readStringMapMap(buffer, size, offset)
+			metadata.Description, newOffset, err = readStringMapMap(nil, buffer, size, offset, nil)
 			if err != nil {
 				return nil, err
 			}
 		case "ip_version":
 			if dataType != dataTypeUint16 {
-				return nil, errors.New("invalid ip_version type: " + strconv.Itoa(int(dataType)))
+				return nil, newUnexpectedTypeError("ip_version", dataTypeUint16, dataType)
 			}
 			newOffset = offset + size
 			metadata.IPVersion = uint16(bytesToUInt64(buffer[offset:newOffset]))
 		case "languages":
 			if dataType != dataTypeSlice {
-				return nil, errors.New("invalid languages type: " + strconv.Itoa(int(dataType)))
+				return nil, newUnexpectedTypeError("languages", dataTypeSlice, dataType)
 			}
 			metadata.Languages, newOffset, err = readStringSlice(buffer, size, offset)
 			if err != nil {
@@ -89,18 +97,21 @@ func readMetadata(buffer []byte) (*Metadata, error) {
 			}
 		case "node_count":
 			if dataType != dataTypeUint32 {
-				return nil, errors.New("invalid node_count type: " + strconv.Itoa(int(dataType)))
+				return nil, newUnexpectedTypeError("node_count", dataTypeUint32, dataType)
 			}
 			newOffset = offset + size
 			metadata.NodeCount = uint32(bytesToUInt64(buffer[offset:newOffset]))
 		case "record_size":
 			if dataType != dataTypeUint16 {
-				return nil, errors.New("invalid record_size type: " + strconv.Itoa(int(dataType)))
+				return nil, newUnexpectedTypeError("record_size", dataTypeUint16, dataType)
 			}
 			newOffset = offset + size
 			metadata.RecordSize = uint16(bytesToUInt64(buffer[offset:newOffset]))
 		default:
-			return nil, errors.New("unknown key: " + string(key) + ", type: " + strconv.Itoa(int(dataType)))
+			newOffset, err = skipUnknownKey(options, "metadata", key, buffer, valueOffset)
+			if err != nil {
+				return nil, err
+			}
 		}
 		offset = newOffset
 	}
@@ -0,0 +1,13 @@
+//go:build !(aix || darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd || solaris)
+
+package geoip2
+
+import "io/ioutil"
+
+func mmapFile(filename string) ([]byte, error) {
+	return ioutil.ReadFile(filename)
+}
+
+func munmap(buffer []byte) error {
+	return nil
+}
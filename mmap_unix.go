@@ -0,0 +1,36 @@
+//go:build aix || darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd || solaris
+
+package geoip2
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+func mmapFile(filename string) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := stat.Size()
+	if size == 0 {
+		return nil, nil
+	}
+	if int64(int(size)) != size {
+		return nil, errors.New("file is too large to be mapped: " + filename)
+	}
+	return syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmap(buffer []byte) error {
+	if len(buffer) == 0 {
+		return nil
+	}
+	return syscall.Munmap(buffer)
+}
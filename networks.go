@@ -0,0 +1,221 @@
+package geoip2
+
+import (
+	"net/netip"
+	"strconv"
+)
+
+const (
+	networkNodeUnknown byte = iota
+	networkNodeVisiting
+	networkNodeEmpty
+	networkNodeData
+)
+
+type networkNode struct {
+	ip   [16]byte
+	bit  uint
+	node uint
+}
+
+// Networks iterates over the networks of a database and their records.
+type Networks[T any] struct {
+	reader  *reader
+	decode  func(offset uint, prefix netip.Prefix) (T, error)
+	nodes   []networkNode
+	network netip.Prefix
+	offset  uint
+	err     error
+	// subtrees holds whether the subtree of every node has a record, so that
+	// nodes sharing an empty subtree are only walked once.
+	subtrees []byte
+}
+
+func newNetworks[T any](reader *reader, decode func(offset uint, prefix netip.Prefix) (T, error)) *Networks[T] {
+	return &Networks[T]{
+		reader: reader,
+		decode: decode,
+		nodes:  []networkNode{{}},
+	}
+}
+
+func newNetworksWithin[T any](reader *reader, prefix netip.Prefix, decode func(offset uint, prefix netip.Prefix) (T, error)) *Networks[T] {
+	networks := &Networks[T]{
+		reader: reader,
+		decode: decode,
+	}
+	node, err := reader.lookupNetworkNode(prefix)
+	if err != nil {
+		networks.err = err
+		return networks
+	}
+	networks.nodes = []networkNode{node}
+	return networks
+}
+
+// Next advances to the next network. It returns false when there are no more
+// networks or when an error occurred, see Err.
+func (n *Networks[T]) Next() bool {
+	if n.err != nil {
+		return false
+	}
+	r := n.reader
+	nodeCount := uint(r.metadata.NodeCount)
+	bitCount := uint(128)
+	if r.metadata.IPVersion == 4 {
+		bitCount = 32
+	}
+	for len(n.nodes) > 0 {
+		current := n.nodes[len(n.nodes)-1]
+		n.nodes = n.nodes[:len(n.nodes)-1]
+		for current.node < nodeCount {
+			if r.metadata.IPVersion == 6 && current.node == r.ipV4Start && current.ip != [16]byte{} {
+				// IPv4-mapped and 6to4 aliases of the IPv4 subtree.
+				break
+			}
+			if n.isEmpty(current.node, current.bit, bitCount) {
+				break
+			}
+			if current.bit >= bitCount {
+				n.err = newInvalidDatabaseError(current.node*r.nodeOffsetMult, "invalid node in search tree")
+				return false
+			}
+			offset := current.node * r.nodeOffsetMult
+			right := networkNode{
+				ip:   current.ip,
+				bit:  current.bit + 1,
+				node: r.readRight(offset),
+			}
+			right.ip[current.bit>>3] |= 1 << (7 - (current.bit % 8))
+			n.nodes = append(n.nodes, right)
+			current.node = r.readLeft(offset)
+			current.bit++
+		}
+		if current.node <= nodeCount {
+			continue
+		}
+		offset := current.node - nodeCount - dataSectionSeparatorSize
+		if offset >= uint(len(r.decoderBuffer)) {
+			n.err = newInvalidDatabaseError(offset, "the search tree points outside the data section: "+strconv.Itoa(int(current.node)))
+			return false
+		}
+		n.network = r.networkPrefix(current.ip, current.bit)
+		n.offset = offset
+		return true
+	}
+	return false
+}
+
+// isEmpty reports whether the subtree of node has no record. Subtrees too deep
+// for the tree or part of a cycle count as not empty, so that Next walks them
+// and reports the invalid node.
+func (n *Networks[T]) isEmpty(node uint, bit uint, bitCount uint) bool {
+	r := n.reader
+	nodeCount := uint(r.metadata.NodeCount)
+	if node == nodeCount {
+		return true
+	}
+	if node > nodeCount || bit >= bitCount {
+		return false
+	}
+	if n.subtrees == nil {
+		n.subtrees = make([]byte, nodeCount)
+	}
+	switch n.subtrees[node] {
+	case networkNodeEmpty:
+		return true
+	case networkNodeVisiting, networkNodeData:
+		return false
+	}
+	n.subtrees[node] = networkNodeVisiting
+	offset := node * r.nodeOffsetMult
+	empty := n.isEmpty(r.readLeft(offset), bit+1, bitCount) && n.isEmpty(r.readRight(offset), bit+1, bitCount)
+	if empty {
+		n.subtrees[node] = networkNodeEmpty
+	} else {
+		n.subtrees[node] = networkNodeData
+	}
+	return empty
+}
+
+// Network returns the current network.
+func (n *Networks[T]) Network() netip.Prefix {
+	return n.network
+}
+
+// Offset returns the data section offset of the record of the current network.
+// Networks sharing a record have the same offset, see Reader.LookupOffset.
+func (n *Networks[T]) Offset() uint {
+	return n.offset
+}
+
+// Record decodes the record of the current network.
+func (n *Networks[T]) Record() (T, error) {
+	return n.decode(n.offset, n.network)
+}
+
+// Decode decodes the record of the current network into result, see
+// Reader.Lookup.
+func (n *Networks[T]) Decode(result interface{}) error {
+	return decodeReflectInto(n.reader.decoderBuffer, n.offset, result)
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (n *Networks[T]) Err() error {
+	return n.err
+}
+
+func (r *reader) networkPrefix(ip [16]byte, bit uint) netip.Prefix {
+	if r.metadata.IPVersion == 4 {
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte{ip[0], ip[1], ip[2], ip[3]}), int(bit))
+	}
+	if bit >= 96 && ip[0]|ip[1]|ip[2]|ip[3]|ip[4]|ip[5]|ip[6]|ip[7]|ip[8]|ip[9]|ip[10]|ip[11] == 0 {
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte{ip[12], ip[13], ip[14], ip[15]}), int(bit-96))
+	}
+	return netip.PrefixFrom(netip.AddrFrom16(ip), int(bit))
+}
+
+func (r *reader) lookupNetworkNode(prefix netip.Prefix) (networkNode, error) {
+	if !prefix.IsValid() {
+		return networkNode{}, ErrInvalidPrefix
+	}
+	prefix = prefix.Masked()
+	addr := prefix.Addr()
+	bitCount := uint(prefix.Bits())
+	if addr.Is4In6() && bitCount >= 96 {
+		addr = addr.Unmap()
+		bitCount -= 96
+	}
+	current := networkNode{}
+	if addr.Is4() {
+		ip := addr.As4()
+		if r.metadata.IPVersion == 6 {
+			copy(current.ip[12:], ip[:])
+			bitCount += 96
+		} else {
+			copy(current.ip[:], ip[:])
+		}
+	} else {
+		if r.metadata.IPVersion == 4 {
+			return networkNode{}, ErrIPv6InIPv4DB
+		}
+		current.ip = addr.As16()
+	}
+	nodeCount := uint(r.metadata.NodeCount)
+	for ; current.bit < bitCount && current.node < nodeCount; current.bit++ {
+		bit := 1 & (current.ip[current.bit>>3] >> (7 - (current.bit % 8)))
+		offset := current.node * r.nodeOffsetMult
+		if bit == 0 {
+			current.node = r.readLeft(offset)
+		} else {
+			current.node = r.readRight(offset)
+		}
+	}
+	if current.bit < bitCount {
+		// The prefix is contained within a network of the database.
+		for i := current.bit; i < 128; i++ {
+			current.ip[i>>3] &^= 1 << (7 - (i % 8))
+		}
+	}
+	return current, nil
+}
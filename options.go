@@ -0,0 +1,72 @@
+package geoip2
+
+type Option func(*options)
+
+type options struct {
+	unknownKey            func(section string, key string) error
+	languages             []string
+	fields                []string
+	skipDatabaseTypeCheck bool
+}
+
+// WithUnknownKeyHandler sets a function that is called for every record key
+// the reader does not know. The section is the name of the map holding the key,
+// e.g. "traits", and is empty for the top level of a record. Unknown keys are
+// skipped unless the handler returns an error, which fails the lookup.
+func WithUnknownKeyHandler(handler func(section string, key string) error) Option {
+	return func(options *options) {
+		options.unknownKey = handler
+	}
+}
+
+// WithLanguages restricts the decoded Names of continents, countries,
+// subdivisions and cities to the given locales. Every locale must be listed
+// in the Languages of the database metadata.
+func WithLanguages(languages ...string) Option {
+	languages = append([]string(nil), languages...)
+	return func(options *options) {
+		options.languages = languages
+	}
+}
+
+// WithFields restricts the decoding of City and Country records to the given
+// top-level keys, e.g. "country" and "location". The other keys are skipped
+// and their fields of the result are left empty. Opening a reader fails for
+// keys its records do not have.
+func WithFields(fields ...string) Option {
+	fields = append([]string(nil), fields...)
+	return func(options *options) {
+		options.fields = fields
+	}
+}
+
+// WithoutDatabaseTypeCheck lets the typed readers open databases of any type,
+// e.g. GeoIP2-City-Africa or GeoIP2-Precision-City, as long as their records
+// follow the schema of the reader.
+func WithoutDatabaseTypeCheck() Option {
+	return func(options *options) {
+		options.skipDatabaseTypeCheck = true
+	}
+}
+
+func newOptions(opts []Option) *options {
+	result := &options{}
+	for _, opt := range opts {
+		opt(result)
+	}
+	return result
+}
+
+func skipUnknownKey(options *options, section string, key []byte, buffer []byte, offset uint) (uint, error) {
+	if options.unknownKey != nil {
+		err := options.unknownKey(section, string(key))
+		if err != nil {
+			return 0, err
+		}
+	}
+	return skipValue(buffer, offset, 0)
+}
+
+func skipField(options *options, key []byte) bool {
+	return options.fields != nil && !containsKey(options.fields, key)
+}
@@ -1,18 +1,13 @@
 package geoip2
 
-import (
-	"errors"
-	"strconv"
-)
-
-func readPostal(postal *Postal, buffer []byte, offset uint) (uint, error) {
+func readPostal(postal *Postal, buffer []byte, offset uint, options *options) (uint, error) {
 	dataType, size, offset, err := readControl(buffer, offset)
 	if err != nil {
 		return 0, err
 	}
 	switch dataType {
 	case dataTypeMap:
-		return readPostalMap(postal, buffer, size, offset)
+		return readPostalMap(postal, buffer, size, offset, options)
 	case dataTypePointer:
 		pointer, newOffset, err := readPointer(buffer, size, offset)
 		if err != nil {
@@ -23,19 +18,19 @@ func readPostal(postal *Postal, buffer []byte, offset uint) (uint, error) {
 			return 0, err
 		}
 		if dataType != dataTypeMap {
-			return 0, errors.New("invalid postal pointer type: " + strconv.Itoa(int(dataType)))
+			return 0, newUnexpectedTypeError("postal", dataTypeMap, dataType)
 		}
-		_, err = readPostalMap(postal, buffer, size, offset)
+		_, err = readPostalMap(postal, buffer, size, offset, options)
 		if err != nil {
 			return 0, err
 		}
 		return newOffset, nil
 	default:
-		return 0, errors.New("invalid postal type: " + strconv.Itoa(int(dataType)))
+		return 0, newUnexpectedTypeError("postal", dataTypeMap, dataType)
 	}
 }
 
-func readPostalMap(postal *Postal, buffer []byte, mapSize uint, offset uint) (uint, error) {
+func readPostalMap(postal *Postal, buffer []byte, mapSize uint, offset uint, options *options) (uint, error) {
 	var key []byte
 	var err error
 	for i := uint(0); i < mapSize; i++ {
@@ -47,15 +42,18 @@ func readPostalMap(postal *Postal, buffer []byte, mapSize uint, offset uint) (ui
 		case "code":
 			postal.Code, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "postal", key)
 			}
 		case "confidence":
 			postal.Confidence, offset, err = readUInt16(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "postal", key)
 			}
 		default:
-			return 0, errors.New("unknown postal key: " + string(key))
+			offset, err = skipUnknownKey(options, "postal", key, buffer, offset)
+			if err != nil {
+				return 0, err
+			}
 		}
 	}
 	return offset, nil
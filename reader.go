@@ -2,13 +2,11 @@ package geoip2
 
 import (
 	"bytes"
-	"errors"
 	"net"
+	"net/netip"
 	"strconv"
 )
 
-var ErrNotFound = errors.New("not found")
-
 type reader struct {
 	metadata          *Metadata
 	buffer            []byte
@@ -17,39 +15,109 @@ type reader struct {
 	ipV4Start         uint
 	ipV4StartBitDepth uint
 	nodeOffsetMult    uint
+	mmap              []byte
+	options           *options
+}
+
+// Close unmaps the database opened by one of the FromFileMmap constructors.
+// Neither the reader nor any result obtained from it may be used after Close.
+func (r *reader) Close() error {
+	if r.mmap == nil {
+		return nil
+	}
+	mmap := r.mmap
+	r.mmap = nil
+	return munmap(mmap)
+}
+
+// Metadata returns the metadata of the database. Like lookup results, it must
+// not be used after a memory-mapped database is closed.
+func (r *reader) Metadata() *Metadata {
+	return r.metadata
+}
+
+// SearchTreeSize returns the size of the search tree in bytes.
+func (r *reader) SearchTreeSize() int {
+	return len(r.nodeBuffer)
+}
+
+// DataSectionSize returns the size of the data section in bytes.
+func (r *reader) DataSectionSize() int {
+	return len(r.decoderBuffer)
+}
+
+func (r *reader) checkDatabaseType(want string, databaseTypes []string) error {
+	if r.options.skipDatabaseTypeCheck {
+		return nil
+	}
+	for _, databaseType := range databaseTypes {
+		if r.metadata.DatabaseType == databaseType {
+			return nil
+		}
+	}
+	return newWrongDatabaseTypeError(want, r.metadata.DatabaseType)
 }
 
-func (r *reader) getOffsetWithPrefix(ip net.IP) (uint, uint, error) {
-	pointer, prefix, err := r.lookupPointer(ip)
+// checkFields checks the fields of WithFields against the top-level keys of
+// the records of the reader.
+func (r *reader) checkFields(fields []string) error {
+	for _, field := range r.options.fields {
+		if !containsKey(fields, []byte(field)) {
+			return &UnknownFieldError{
+				Field: field,
+			}
+		}
+	}
+	return nil
+}
+
+func (r *reader) getOffsetWithPrefix(ip net.IP) (uint, netip.Prefix, error) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return 0, netip.Prefix{}, ErrInvalidIP
+	}
+	return r.getAddrOffsetWithPrefix(addr)
+}
+
+func (r *reader) getAddrOffsetWithPrefix(addr netip.Addr) (uint, netip.Prefix, error) {
+	pointer, bitCount, err := r.lookupAddrPointer(addr)
 	if err != nil {
-		return 0, 0, err
+		return 0, netip.Prefix{}, err
 	}
-	offset := pointer - uint(r.metadata.NodeCount) - uint(dataSectionSeparatorSize)
-	if offset >= uint(len(r.buffer)) {
-		return 0, 0, errors.New("the MaxMind DB search tree is corrupt: " + strconv.Itoa(int(pointer)))
+	offset, err := r.getPointerOffset(pointer)
+	if err != nil {
+		return 0, netip.Prefix{}, err
 	}
+	// traverseTree stops at the last bit of addr, so bitCount is a valid length.
+	prefix, _ := addr.Unmap().Prefix(int(bitCount))
 	return offset, prefix, nil
 }
 
-func (r *reader) getOffset(ip net.IP) (uint, error) {
-	offset, _, err := r.getOffsetWithPrefix(ip)
-	if err != nil {
-		return 0, err
+func (r *reader) getPointerOffset(pointer uint) (uint, error) {
+	offset := pointer - uint(r.metadata.NodeCount) - uint(dataSectionSeparatorSize)
+	if offset >= uint(len(r.decoderBuffer)) {
+		return 0, newInvalidDatabaseError(offset, "the search tree points outside the data section: "+strconv.Itoa(int(pointer)))
 	}
 	return offset, nil
 }
 
-func (r *reader) lookupPointer(ip net.IP) (uint, uint, error) {
-	if ip == nil {
-		return 0, 0, errors.New("IP cannot be nil")
+func (r *reader) lookupAddrPointer(addr netip.Addr) (uint, uint, error) {
+	if !addr.IsValid() {
+		return 0, 0, ErrInvalidIP
 	}
-	ipV4 := ip.To4()
-	if ipV4 != nil {
-		ip = ipV4
+	addr = addr.Unmap()
+	if addr.Is4() {
+		ip := addr.As4()
+		return r.traverseTree(ip[:])
 	}
-	if len(ip) == 16 && r.metadata.IPVersion == 4 {
-		return 0, 0, errors.New("cannot look up an IPv6 address in an IPv4-only database")
+	if r.metadata.IPVersion == 4 {
+		return 0, 0, ErrIPv6InIPv4DB
 	}
+	ip := addr.As16()
+	return r.traverseTree(ip[:])
+}
+
+func (r *reader) traverseTree(ip []byte) (uint, uint, error) {
 	bitCount := uint(len(ip)) * 8
 	node := uint(0)
 	if bitCount == 32 {
@@ -71,7 +139,7 @@ func (r *reader) lookupPointer(ip net.IP) (uint, uint, error) {
 	} else if node > nodeCount {
 		return node, i, nil
 	}
-	return 0, 0, errors.New("invalid node in search tree")
+	return 0, 0, newInvalidDatabaseError(node*r.nodeOffsetMult, "invalid node in search tree")
 }
 
 func (r *reader) readLeft(nodeNumber uint) uint {
@@ -96,21 +164,35 @@ func (r *reader) readRight(nodeNumber uint) uint {
 	}
 }
 
-func newReader(buffer []byte) (*reader, error) {
+func newReader(buffer []byte, opts []Option) (*reader, error) {
 	if len(buffer) == 0 {
-		return nil, errors.New("buffer is empty")
+		return nil, newInvalidDatabaseError(0, "buffer is empty")
 	}
 
 	metadataStart := bytes.LastIndex(buffer, metadataStartMarker)
-	metadata, err := readMetadata(buffer[metadataStart+len(metadataStartMarker):])
+	if metadataStart == -1 {
+		return nil, newInvalidDatabaseError(0, "metadata not found")
+	}
+	options := newOptions(opts)
+	metadata, err := readMetadata(buffer[metadataStart+len(metadataStartMarker):], options)
 	if err != nil {
 		return nil, err
 	}
+	for _, language := range options.languages {
+		if !containsKey(metadata.Languages, []byte(language)) {
+			return nil, &UnknownLanguageError{
+				Language: language,
+			}
+		}
+	}
+	if metadata.RecordSize != 24 && metadata.RecordSize != 28 && metadata.RecordSize != 32 {
+		return nil, newInvalidDatabaseError(0, "invalid record size: "+strconv.Itoa(int(metadata.RecordSize)))
+	}
 	nodeOffsetMult := uint(metadata.RecordSize) / 4
 	searchTreeSize := uint(metadata.NodeCount) * nodeOffsetMult
 	dataSectionStart := searchTreeSize + dataSectionSeparatorSize
 	if dataSectionStart > uint(metadataStart) {
-		return nil, errors.New("the MaxMind DB contains invalid metadata")
+		return nil, newInvalidDatabaseError(0, "the search tree overlaps the metadata")
 	}
 	reader := &reader{
 		metadata:       metadata,
@@ -118,6 +200,7 @@ func newReader(buffer []byte) (*reader, error) {
 		decoderBuffer:  buffer[searchTreeSize+dataSectionSeparatorSize : metadataStart],
 		nodeBuffer:     buffer[:searchTreeSize],
 		nodeOffsetMult: nodeOffsetMult,
+		options:        options,
 	}
 	if metadata.IPVersion == 6 {
 		node := uint(0)
@@ -1,71 +1,150 @@
 package geoip2
 
 import (
-	"errors"
 	"io/ioutil"
 	"net"
-	"strconv"
+	"net/netip"
 )
 
 type AnonymousIPReader struct {
 	*reader
 }
 
+var anonymousIPDatabaseTypes = []string{
+	"GeoIP2-Anonymous-IP",
+}
+
 func (r *AnonymousIPReader) Lookup(ip net.IP) (*AnonymousIP, error) {
-	offset, err := r.getOffset(ip)
+	offset, prefix, err := r.getOffsetWithPrefix(ip)
 	if err != nil {
 		return nil, err
 	}
-	dataType, size, offset, err := readControl(r.decoderBuffer, offset)
+	return r.decode(offset, prefix)
+}
+
+func (r *AnonymousIPReader) LookupAddr(addr netip.Addr) (*AnonymousIP, error) {
+	offset, prefix, err := r.getAddrOffsetWithPrefix(addr)
 	if err != nil {
 		return nil, err
 	}
+	return r.decode(offset, prefix)
+}
+
+// LookupInto looks up ip and decodes its record into result.
+func (r *AnonymousIPReader) LookupInto(ip net.IP, result *AnonymousIP) error {
+	offset, prefix, err := r.getOffsetWithPrefix(ip)
+	if err != nil {
+		return err
+	}
+	return r.decodeInto(result, offset, prefix)
+}
+
+// LookupAddrInto is like LookupInto but takes a netip.Addr.
+func (r *AnonymousIPReader) LookupAddrInto(addr netip.Addr, result *AnonymousIP) error {
+	offset, prefix, err := r.getAddrOffsetWithPrefix(addr)
+	if err != nil {
+		return err
+	}
+	return r.decodeInto(result, offset, prefix)
+}
+
+func (r *AnonymousIPReader) decode(offset uint, prefix netip.Prefix) (*AnonymousIP, error) {
 	result := &AnonymousIP{}
+	err := r.decodeInto(result, offset, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *AnonymousIPReader) decodeInto(result *AnonymousIP, offset uint, prefix netip.Prefix) error {
+	dataType, size, offset, err := readControl(r.decoderBuffer, offset)
+	if err != nil {
+		return err
+	}
+	*result = AnonymousIP{
+		Prefix: prefix,
+	}
 	switch dataType {
 	case dataTypeMap:
-		_, err = readAnonymousIPMap(result, r.decoderBuffer, size, offset)
+		_, err = readAnonymousIPMap(result, r.decoderBuffer, size, offset, r.options)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	case dataTypePointer:
 		pointer, _, err := readPointer(r.decoderBuffer, size, offset)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		dataType, size, offset, err := readControl(r.decoderBuffer, pointer)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if dataType != dataTypeMap {
-			return nil, errors.New("invalid Anonymous-IP pointer type: " + strconv.Itoa(int(dataType)))
+			return newUnexpectedTypeError("Anonymous-IP", dataTypeMap, dataType)
 		}
-		_, err = readAnonymousIPMap(result, r.decoderBuffer, size, offset)
+		_, err = readAnonymousIPMap(result, r.decoderBuffer, size, offset, r.options)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	default:
-		return nil, errors.New("invalid Anonymous-IP type: " + strconv.Itoa(int(dataType)))
+		return newUnexpectedTypeError("Anonymous-IP", dataTypeMap, dataType)
 	}
-	return result, nil
+	return nil
+}
+
+func (r *AnonymousIPReader) Networks() *Networks[*AnonymousIP] {
+	return newNetworks(r.reader, r.decode)
+}
+
+func (r *AnonymousIPReader) NetworksWithin(prefix netip.Prefix) *Networks[*AnonymousIP] {
+	return newNetworksWithin(r.reader, prefix, r.decode)
 }
 
-func NewAnonymousIPReader(buffer []byte) (*AnonymousIPReader, error) {
-	reader, err := newReader(buffer)
+// AnonymousIP returns a AnonymousIPReader sharing the database of r, which must be one of the
+// Anonymous-IP database types unless r was opened WithoutDatabaseTypeCheck.
+func (r *Reader) AnonymousIP() (*AnonymousIPReader, error) {
+	err := r.checkDatabaseType("Anonymous-IP", anonymousIPDatabaseTypes)
 	if err != nil {
 		return nil, err
 	}
-	if reader.metadata.DatabaseType != "GeoIP2-Anonymous-IP" {
-		return nil, errors.New("wrong MaxMind DB Anonymous-IP type: " + reader.metadata.DatabaseType)
+	return &AnonymousIPReader{
+		reader: r.reader,
+	}, nil
+}
+
+func NewAnonymousIPReader(buffer []byte, opts ...Option) (*AnonymousIPReader, error) {
+	reader, err := newReader(buffer, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = reader.checkDatabaseType("Anonymous-IP", anonymousIPDatabaseTypes)
+	if err != nil {
+		return nil, err
 	}
 	return &AnonymousIPReader{
 		reader: reader,
 	}, nil
 }
 
-func NewAnonymousIPReaderFromFile(filename string) (*AnonymousIPReader, error) {
+func NewAnonymousIPReaderFromFile(filename string, opts ...Option) (*AnonymousIPReader, error) {
 	buffer, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	return NewAnonymousIPReader(buffer)
+	return NewAnonymousIPReader(buffer, opts...)
+}
+
+func NewAnonymousIPReaderFromFileMmap(filename string, opts ...Option) (*AnonymousIPReader, error) {
+	buffer, err := mmapFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewAnonymousIPReader(buffer, opts...)
+	if err != nil {
+		_ = munmap(buffer)
+		return nil, err
+	}
+	reader.mmap = buffer
+	return reader, nil
 }
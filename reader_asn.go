@@ -1,74 +1,157 @@
 package geoip2
 
 import (
-	"errors"
 	"io/ioutil"
 	"net"
-	"strconv"
+	"net/netip"
 )
 
 type ASNReader struct {
 	*reader
 }
 
+var asnDatabaseTypes = []string{
+	"GeoLite2-ASN",
+	"DBIP-ASN-Lite",
+	"DBIP-ASN-Lite (compat=GeoLite2-ASN)",
+}
+
 func (r *ASNReader) Lookup(ip net.IP) (*ASN, error) {
 	offset, prefix, err := r.getOffsetWithPrefix(ip)
 	if err != nil {
 		return nil, err
 	}
-	dataType, size, offset, err := readControl(r.decoderBuffer, offset)
+	return r.decode(offset, prefix)
+}
+
+func (r *ASNReader) LookupAddr(addr netip.Addr) (*ASN, error) {
+	offset, prefix, err := r.getAddrOffsetWithPrefix(addr)
 	if err != nil {
 		return nil, err
 	}
+	return r.decode(offset, prefix)
+}
+
+// LookupInto looks up ip and decodes its record into result.
+func (r *ASNReader) LookupInto(ip net.IP, result *ASN) error {
+	offset, prefix, err := r.getOffsetWithPrefix(ip)
+	if err != nil {
+		return err
+	}
+	return r.decodeInto(result, offset, prefix)
+}
+
+// LookupAddrInto is like LookupInto but takes a netip.Addr.
+func (r *ASNReader) LookupAddrInto(addr netip.Addr, result *ASN) error {
+	offset, prefix, err := r.getAddrOffsetWithPrefix(addr)
+	if err != nil {
+		return err
+	}
+	return r.decodeInto(result, offset, prefix)
+}
+
+func (r *ASNReader) decode(offset uint, prefix netip.Prefix) (*ASN, error) {
 	result := &ASN{}
-	result.Network = getNetworkString(ip, prefix)
+	err := r.decodeInto(result, offset, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *ASNReader) decodeInto(result *ASN, offset uint, prefix netip.Prefix) error {
+	dataType, size, offset, err := readControl(r.decoderBuffer, offset)
+	if err != nil {
+		return err
+	}
+	network := result.Network
+	if network == "" || result.Prefix != prefix {
+		network = prefix.String()
+	}
+	*result = ASN{
+		Network: network,
+		Prefix:  prefix,
+	}
 	switch dataType {
 	case dataTypeMap:
-		_, err = readASNMap(result, r.decoderBuffer, size, offset)
+		_, err = readASNMap(result, r.decoderBuffer, size, offset, r.options)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	case dataTypePointer:
 		pointer, _, err := readPointer(r.decoderBuffer, size, offset)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		dataType, size, offset, err := readControl(r.decoderBuffer, pointer)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if dataType != dataTypeMap {
-			return nil, errors.New("invalid ASN pointer type: " + strconv.Itoa(int(dataType)))
+			return newUnexpectedTypeError("ASN", dataTypeMap, dataType)
 		}
-		_, err = readASNMap(result, r.decoderBuffer, size, offset)
+		_, err = readASNMap(result, r.decoderBuffer, size, offset, r.options)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	default:
-		return nil, errors.New("invalid ASN type: " + strconv.Itoa(int(dataType)))
+		return newUnexpectedTypeError("ASN", dataTypeMap, dataType)
 	}
-	return result, nil
+	return nil
+}
+
+func (r *ASNReader) Networks() *Networks[*ASN] {
+	return newNetworks(r.reader, r.decode)
+}
+
+func (r *ASNReader) NetworksWithin(prefix netip.Prefix) *Networks[*ASN] {
+	return newNetworksWithin(r.reader, prefix, r.decode)
+}
+
+// ASN returns a ASNReader sharing the database of r, which must be one of the
+// ASN database types unless r was opened WithoutDatabaseTypeCheck.
+func (r *Reader) ASN() (*ASNReader, error) {
+	err := r.checkDatabaseType("ASN", asnDatabaseTypes)
+	if err != nil {
+		return nil, err
+	}
+	return &ASNReader{
+		reader: r.reader,
+	}, nil
 }
 
-func NewASNReader(buffer []byte) (*ASNReader, error) {
-	reader, err := newReader(buffer)
+func NewASNReader(buffer []byte, opts ...Option) (*ASNReader, error) {
+	reader, err := newReader(buffer, opts)
 	if err != nil {
 		return nil, err
 	}
-	if reader.metadata.DatabaseType != "GeoLite2-ASN" &&
-		reader.metadata.DatabaseType != "DBIP-ASN-Lite" &&
-		reader.metadata.DatabaseType != "DBIP-ASN-Lite (compat=GeoLite2-ASN)" {
-		return nil, errors.New("wrong MaxMind DB ASN type: " + reader.metadata.DatabaseType)
+	err = reader.checkDatabaseType("ASN", asnDatabaseTypes)
+	if err != nil {
+		return nil, err
 	}
 	return &ASNReader{
 		reader: reader,
 	}, nil
 }
 
-func NewASNReaderFromFile(filename string) (*ASNReader, error) {
+func NewASNReaderFromFile(filename string, opts ...Option) (*ASNReader, error) {
 	buffer, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	return NewASNReader(buffer)
+	return NewASNReader(buffer, opts...)
+}
+
+func NewASNReaderFromFileMmap(filename string, opts ...Option) (*ASNReader, error) {
+	buffer, err := mmapFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewASNReader(buffer, opts...)
+	if err != nil {
+		_ = munmap(buffer)
+		return nil, err
+	}
+	reader.mmap = buffer
+	return reader, nil
 }
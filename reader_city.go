@@ -1,116 +1,242 @@
 package geoip2
 
 import (
-	"errors"
 	"io/ioutil"
 	"net"
-	"strconv"
+	"net/netip"
 )
 
 type CityReader struct {
 	*reader
 }
 
+var cityDatabaseTypes = []string{
+	"GeoIP2-City",
+	"GeoLite2-City",
+	"GeoIP2-Enterprise",
+	"DBIP-City-Lite",
+}
+
+var cityFields = []string{
+	"city",
+	"continent",
+	"country",
+	"location",
+	"postal",
+	"registered_country",
+	"represented_country",
+	"subdivisions",
+	"traits",
+}
+
 func (r *CityReader) Lookup(ip net.IP) (*CityResult, error) {
-	offset, err := r.getOffset(ip)
+	offset, prefix, err := r.getOffsetWithPrefix(ip)
 	if err != nil {
 		return nil, err
 	}
-	dataType, size, offset, err := readControl(r.decoderBuffer, offset)
+	return r.decode(offset, prefix)
+}
+
+func (r *CityReader) LookupAddr(addr netip.Addr) (*CityResult, error) {
+	offset, prefix, err := r.getAddrOffsetWithPrefix(addr)
 	if err != nil {
 		return nil, err
 	}
+	return r.decode(offset, prefix)
+}
+
+// LookupInto looks up ip and decodes its record into result, reusing the
+// maps and slices result already holds.
+func (r *CityReader) LookupInto(ip net.IP, result *CityResult) error {
+	offset, prefix, err := r.getOffsetWithPrefix(ip)
+	if err != nil {
+		return err
+	}
+	return r.decodeInto(result, offset, prefix)
+}
+
+// LookupAddrInto is like LookupInto but takes a netip.Addr.
+func (r *CityReader) LookupAddrInto(addr netip.Addr, result *CityResult) error {
+	offset, prefix, err := r.getAddrOffsetWithPrefix(addr)
+	if err != nil {
+		return err
+	}
+	return r.decodeInto(result, offset, prefix)
+}
+
+func (r *CityReader) decode(offset uint, prefix netip.Prefix) (*CityResult, error) {
+	result := &CityResult{}
+	err := r.decodeInto(result, offset, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *CityReader) decodeInto(result *CityResult, offset uint, prefix netip.Prefix) error {
+	dataType, size, offset, err := readControl(r.decoderBuffer, offset)
+	if err != nil {
+		return err
+	}
 	if dataType != dataTypeMap {
-		return nil, errors.New("invalid City type: " + strconv.Itoa(int(dataType)))
+		return newUnexpectedTypeError("City", dataTypeMap, dataType)
 	}
 	var key []byte
-	result := &CityResult{}
+	result.reset()
+	result.Prefix = prefix
 	for i := uint(0); i < size; i++ {
 		key, offset, err = readMapKey(r.decoderBuffer, offset)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if skipField(r.options, key) {
+			offset, err = skipValue(r.decoderBuffer, offset, 0)
+			if err != nil {
+				return err
+			}
+			continue
 		}
 		switch b2s(key) {
 		case "city":
-			offset, err = readCity(&result.City, r.decoderBuffer, offset)
+			offset, err = readCity(&result.City, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		case "continent":
-			offset, err = readContinent(&result.Continent, r.decoderBuffer, offset)
+			offset, err = readContinent(&result.Continent, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		case "country":
-			offset, err = readCountry(&result.Country, r.decoderBuffer, offset)
+			offset, err = readCountry(&result.Country, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		case "location":
-			offset, err = readLocation(&result.Location, r.decoderBuffer, offset)
+			offset, err = readLocation(&result.Location, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		case "postal":
-			offset, err = readPostal(&result.Postal, r.decoderBuffer, offset)
+			offset, err = readPostal(&result.Postal, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		case "registered_country":
-			offset, err = readCountry(&result.RegisteredCountry, r.decoderBuffer, offset)
+			offset, err = readCountry(&result.RegisteredCountry, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		case "represented_country":
-			offset, err = readCountry(&result.RepresentedCountry, r.decoderBuffer, offset)
+			offset, err = readCountry(&result.RepresentedCountry, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		case "subdivisions":
-			result.Subdivisions, offset, err = readSubdivisions(r.decoderBuffer, offset)
+			result.Subdivisions, offset, err = readSubdivisions(result.Subdivisions, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		case "traits":
-			offset, err = readTraits(&result.Traits, r.decoderBuffer, offset)
+			offset, err = readTraits(&result.Traits, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		default:
-			return nil, errors.New("unknown City response key: " + string(key) + ", type: " + strconv.Itoa(int(dataType)))
+			offset, err = skipUnknownKey(r.options, "", key, r.decoderBuffer, offset)
+			if err != nil {
+				return err
+			}
 		}
 	}
-	return result, nil
+	return nil
+}
+
+func (result *CityResult) reset() {
+	result.Continent.reset()
+	result.Country.reset()
+	result.Subdivisions = result.Subdivisions[:0]
+	result.City.reset()
+	result.Location = Location{}
+	result.Postal = Postal{}
+	result.RegisteredCountry.reset()
+	result.RepresentedCountry.reset()
+	result.Traits = Traits{}
+	result.Prefix = netip.Prefix{}
+}
+
+func (r *CityReader) Networks() *Networks[*CityResult] {
+	return newNetworks(r.reader, r.decode)
+}
+
+func (r *CityReader) NetworksWithin(prefix netip.Prefix) *Networks[*CityResult] {
+	return newNetworksWithin(r.reader, prefix, r.decode)
+}
+
+// City returns a CityReader sharing the database of r, which must be one of the
+// City database types unless r was opened WithoutDatabaseTypeCheck.
+func (r *Reader) City() (*CityReader, error) {
+	err := r.checkDatabaseType("City", cityDatabaseTypes)
+	if err != nil {
+		return nil, err
+	}
+	err = r.checkFields(cityFields)
+	if err != nil {
+		return nil, err
+	}
+	return &CityReader{
+		reader: r.reader,
+	}, nil
 }
 
-func NewCityReader(buffer []byte) (*CityReader, error) {
-	reader, err := newReader(buffer)
+func NewCityReader(buffer []byte, opts ...Option) (*CityReader, error) {
+	reader, err := newReader(buffer, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = reader.checkDatabaseType("City", cityDatabaseTypes)
 	if err != nil {
 		return nil, err
 	}
-	if reader.metadata.DatabaseType != "GeoIP2-City" &&
-		reader.metadata.DatabaseType != "GeoLite2-City" &&
-		reader.metadata.DatabaseType != "GeoIP2-Enterprise" &&
-		reader.metadata.DatabaseType != "DBIP-City-Lite" {
-		return nil, errors.New("wrong MaxMind DB City type: " + reader.metadata.DatabaseType)
+	err = reader.checkFields(cityFields)
+	if err != nil {
+		return nil, err
 	}
 	return &CityReader{
 		reader: reader,
 	}, nil
 }
 
-func NewCityReaderFromFile(filename string) (*CityReader, error) {
+func NewCityReaderFromFile(filename string, opts ...Option) (*CityReader, error) {
 	buffer, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	return NewCityReader(buffer)
+	return NewCityReader(buffer, opts...)
+}
+
+func NewCityReaderFromFileMmap(filename string, opts ...Option) (*CityReader, error) {
+	buffer, err := mmapFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewCityReader(buffer, opts...)
+	if err != nil {
+		_ = munmap(buffer)
+		return nil, err
+	}
+	reader.mmap = buffer
+	return reader, nil
+}
+
+func NewEnterpriseReader(buffer []byte, opts ...Option) (*CityReader, error) {
+	return NewCityReader(buffer, opts...)
 }
 
-func NewEnterpriseReader(buffer []byte) (*CityReader, error) {
-	return NewCityReader(buffer)
+func NewEnterpriseReaderFromFile(filename string, opts ...Option) (*CityReader, error) {
+	return NewCityReaderFromFile(filename, opts...)
 }
 
-func NewEnterpriseReaderFromFile(filename string) (*CityReader, error) {
-	return NewCityReaderFromFile(filename)
+func NewEnterpriseReaderFromFileMmap(filename string, opts ...Option) (*CityReader, error) {
+	return NewCityReaderFromFileMmap(filename, opts...)
 }
@@ -1,21 +1,54 @@
 package geoip2
 
 import (
-	"errors"
 	"io/ioutil"
 	"net"
-	"strconv"
+	"net/netip"
 )
 
 type ConnectionTypeReader struct {
 	*reader
 }
 
+var connectionTypeDatabaseTypes = []string{
+	"GeoIP2-Connection-Type",
+}
+
 func (r *ConnectionTypeReader) Lookup(ip net.IP) (string, error) {
-	offset, err := r.getOffset(ip)
+	result, _, err := r.LookupWithPrefix(ip)
+	return result, err
+}
+
+func (r *ConnectionTypeReader) LookupWithPrefix(ip net.IP) (string, netip.Prefix, error) {
+	offset, prefix, err := r.getOffsetWithPrefix(ip)
 	if err != nil {
-		return "", err
+		return "", netip.Prefix{}, err
 	}
+	result, err := r.decode(offset, prefix)
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	return result, prefix, nil
+}
+
+func (r *ConnectionTypeReader) LookupAddr(addr netip.Addr) (string, error) {
+	result, _, err := r.LookupAddrWithPrefix(addr)
+	return result, err
+}
+
+func (r *ConnectionTypeReader) LookupAddrWithPrefix(addr netip.Addr) (string, netip.Prefix, error) {
+	offset, prefix, err := r.getAddrOffsetWithPrefix(addr)
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	result, err := r.decode(offset, prefix)
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	return result, prefix, nil
+}
+
+func (r *ConnectionTypeReader) decode(offset uint, _ netip.Prefix) (string, error) {
 	dataType, size, offset, err := readControl(r.decoderBuffer, offset)
 	if err != nil {
 		return "", err
@@ -23,7 +56,7 @@ func (r *ConnectionTypeReader) Lookup(ip net.IP) (string, error) {
 	result := &ConnectionType{}
 	switch dataType {
 	case dataTypeMap:
-		_, err = readConnectionTypeMap(result, r.decoderBuffer, size, offset)
+		_, err = readConnectionTypeMap(result, r.decoderBuffer, size, offset, r.options)
 		if err != nil {
 			return "", err
 		}
@@ -37,35 +70,70 @@ func (r *ConnectionTypeReader) Lookup(ip net.IP) (string, error) {
 			return "", err
 		}
 		if dataType != dataTypeMap {
-			return "", errors.New("invalid Connection-Type pointer type: " + strconv.Itoa(int(dataType)))
+			return "", newUnexpectedTypeError("Connection-Type", dataTypeMap, dataType)
 		}
-		_, err = readConnectionTypeMap(result, r.decoderBuffer, size, offset)
+		_, err = readConnectionTypeMap(result, r.decoderBuffer, size, offset, r.options)
 		if err != nil {
 			return "", err
 		}
 	default:
-		return "", errors.New("invalid Connection-Type type: " + strconv.Itoa(int(dataType)))
+		return "", newUnexpectedTypeError("Connection-Type", dataTypeMap, dataType)
 	}
 	return result.ConnectionType, nil
 }
 
-func NewConnectionTypeReader(buffer []byte) (*ConnectionTypeReader, error) {
-	reader, err := newReader(buffer)
+func (r *ConnectionTypeReader) Networks() *Networks[string] {
+	return newNetworks(r.reader, r.decode)
+}
+
+func (r *ConnectionTypeReader) NetworksWithin(prefix netip.Prefix) *Networks[string] {
+	return newNetworksWithin(r.reader, prefix, r.decode)
+}
+
+// ConnectionType returns a ConnectionTypeReader sharing the database of r, which must be one of the
+// Connection-Type database types unless r was opened WithoutDatabaseTypeCheck.
+func (r *Reader) ConnectionType() (*ConnectionTypeReader, error) {
+	err := r.checkDatabaseType("Connection-Type", connectionTypeDatabaseTypes)
 	if err != nil {
 		return nil, err
 	}
-	if reader.metadata.DatabaseType != "GeoIP2-Connection-Type" {
-		return nil, errors.New("wrong MaxMind DB Connection-Type type: " + reader.metadata.DatabaseType)
+	return &ConnectionTypeReader{
+		reader: r.reader,
+	}, nil
+}
+
+func NewConnectionTypeReader(buffer []byte, opts ...Option) (*ConnectionTypeReader, error) {
+	reader, err := newReader(buffer, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = reader.checkDatabaseType("Connection-Type", connectionTypeDatabaseTypes)
+	if err != nil {
+		return nil, err
 	}
 	return &ConnectionTypeReader{
 		reader: reader,
 	}, nil
 }
 
-func NewConnectionTypeReaderFromFile(filename string) (*ConnectionTypeReader, error) {
+func NewConnectionTypeReaderFromFile(filename string, opts ...Option) (*ConnectionTypeReader, error) {
 	buffer, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	return NewConnectionTypeReader(buffer)
+	return NewConnectionTypeReader(buffer, opts...)
+}
+
+func NewConnectionTypeReaderFromFileMmap(filename string, opts ...Option) (*ConnectionTypeReader, error) {
+	buffer, err := mmapFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewConnectionTypeReader(buffer, opts...)
+	if err != nil {
+		_ = munmap(buffer)
+		return nil, err
+	}
+	reader.mmap = buffer
+	return reader, nil
 }
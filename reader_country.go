@@ -1,88 +1,202 @@
 package geoip2
 
 import (
-	"errors"
 	"io/ioutil"
 	"net"
-	"strconv"
+	"net/netip"
 )
 
 type CountryReader struct {
 	*reader
 }
 
+var countryDatabaseTypes = []string{
+	"GeoIP2-Country",
+	"GeoLite2-Country",
+	"DBIP-Country",
+	"DBIP-Country-Lite",
+}
+
+var countryFields = []string{
+	"continent",
+	"country",
+	"registered_country",
+	"represented_country",
+	"traits",
+}
+
 func (r *CountryReader) Lookup(ip net.IP) (*CountryResult, error) {
-	offset, err := r.getOffset(ip)
+	offset, prefix, err := r.getOffsetWithPrefix(ip)
 	if err != nil {
 		return nil, err
 	}
-	dataType, size, offset, err := readControl(r.decoderBuffer, offset)
+	return r.decode(offset, prefix)
+}
+
+func (r *CountryReader) LookupAddr(addr netip.Addr) (*CountryResult, error) {
+	offset, prefix, err := r.getAddrOffsetWithPrefix(addr)
+	if err != nil {
+		return nil, err
+	}
+	return r.decode(offset, prefix)
+}
+
+// LookupInto looks up ip and decodes its record into result, reusing the
+// maps and slices result already holds.
+func (r *CountryReader) LookupInto(ip net.IP, result *CountryResult) error {
+	offset, prefix, err := r.getOffsetWithPrefix(ip)
+	if err != nil {
+		return err
+	}
+	return r.decodeInto(result, offset, prefix)
+}
+
+// LookupAddrInto is like LookupInto but takes a netip.Addr.
+func (r *CountryReader) LookupAddrInto(addr netip.Addr, result *CountryResult) error {
+	offset, prefix, err := r.getAddrOffsetWithPrefix(addr)
+	if err != nil {
+		return err
+	}
+	return r.decodeInto(result, offset, prefix)
+}
+
+func (r *CountryReader) decode(offset uint, prefix netip.Prefix) (*CountryResult, error) {
+	result := &CountryResult{}
+	err := r.decodeInto(result, offset, prefix)
 	if err != nil {
 		return nil, err
 	}
+	return result, nil
+}
+
+func (r *CountryReader) decodeInto(result *CountryResult, offset uint, prefix netip.Prefix) error {
+	dataType, size, offset, err := readControl(r.decoderBuffer, offset)
+	if err != nil {
+		return err
+	}
 	if dataType != dataTypeMap {
-		return nil, errors.New("invalid Country type: " + strconv.Itoa(int(dataType)))
+		return newUnexpectedTypeError("Country", dataTypeMap, dataType)
 	}
 	var key []byte
-	result := &CountryResult{}
+	result.reset()
+	result.Prefix = prefix
 	for i := uint(0); i < size; i++ {
 		key, offset, err = readMapKey(r.decoderBuffer, offset)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if skipField(r.options, key) {
+			offset, err = skipValue(r.decoderBuffer, offset, 0)
+			if err != nil {
+				return err
+			}
+			continue
 		}
 		switch b2s(key) {
 		case "continent":
-			offset, err = readContinent(&result.Continent, r.decoderBuffer, offset)
+			offset, err = readContinent(&result.Continent, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		case "country":
-			offset, err = readCountry(&result.Country, r.decoderBuffer, offset)
+			offset, err = readCountry(&result.Country, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		case "registered_country":
-			offset, err = readCountry(&result.RegisteredCountry, r.decoderBuffer, offset)
+			offset, err = readCountry(&result.RegisteredCountry, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		case "represented_country":
-			offset, err = readCountry(&result.RepresentedCountry, r.decoderBuffer, offset)
+			offset, err = readCountry(&result.RepresentedCountry, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		case "traits":
-			offset, err = readTraits(&result.Traits, r.decoderBuffer, offset)
+			offset, err = readTraits(&result.Traits, r.decoderBuffer, offset, r.options)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		default:
-			return nil, errors.New("unknown Country response key: " + string(key) + ", type: " + strconv.Itoa(int(dataType)))
+			offset, err = skipUnknownKey(r.options, "", key, r.decoderBuffer, offset)
+			if err != nil {
+				return err
+			}
 		}
 	}
-	return result, nil
+	return nil
+}
+
+func (result *CountryResult) reset() {
+	result.Continent.reset()
+	result.Country.reset()
+	result.RegisteredCountry.reset()
+	result.RepresentedCountry.reset()
+	result.Traits = Traits{}
+	result.Prefix = netip.Prefix{}
+}
+
+func (r *CountryReader) Networks() *Networks[*CountryResult] {
+	return newNetworks(r.reader, r.decode)
+}
+
+func (r *CountryReader) NetworksWithin(prefix netip.Prefix) *Networks[*CountryResult] {
+	return newNetworksWithin(r.reader, prefix, r.decode)
 }
 
-func NewCountryReader(buffer []byte) (*CountryReader, error) {
-	reader, err := newReader(buffer)
+// Country returns a CountryReader sharing the database of r, which must be one of the
+// Country database types unless r was opened WithoutDatabaseTypeCheck.
+func (r *Reader) Country() (*CountryReader, error) {
+	err := r.checkDatabaseType("Country", countryDatabaseTypes)
 	if err != nil {
 		return nil, err
 	}
-	if reader.metadata.DatabaseType != "GeoIP2-Country" &&
-		reader.metadata.DatabaseType != "GeoLite2-Country" &&
-		reader.metadata.DatabaseType != "DBIP-Country" &&
-		reader.metadata.DatabaseType != "DBIP-Country-Lite" {
-		return nil, errors.New("wrong MaxMind DB Country type: " + reader.metadata.DatabaseType)
+	err = r.checkFields(countryFields)
+	if err != nil {
+		return nil, err
+	}
+	return &CountryReader{
+		reader: r.reader,
+	}, nil
+}
+
+func NewCountryReader(buffer []byte, opts ...Option) (*CountryReader, error) {
+	reader, err := newReader(buffer, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = reader.checkDatabaseType("Country", countryDatabaseTypes)
+	if err != nil {
+		return nil, err
+	}
+	err = reader.checkFields(countryFields)
+	if err != nil {
+		return nil, err
 	}
 	return &CountryReader{
 		reader: reader,
 	}, nil
 }
 
-func NewCountryReaderFromFile(filename string) (*CountryReader, error) {
+func NewCountryReaderFromFile(filename string, opts ...Option) (*CountryReader, error) {
 	buffer, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	return NewCountryReader(buffer)
+	return NewCountryReader(buffer, opts...)
+}
+
+func NewCountryReaderFromFileMmap(filename string, opts ...Option) (*CountryReader, error) {
+	buffer, err := mmapFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewCountryReader(buffer, opts...)
+	if err != nil {
+		_ = munmap(buffer)
+		return nil, err
+	}
+	reader.mmap = buffer
+	return reader, nil
 }
@@ -1,21 +1,54 @@
 package geoip2
 
 import (
-	"errors"
 	"io/ioutil"
 	"net"
-	"strconv"
+	"net/netip"
 )
 
 type DomainReader struct {
 	*reader
 }
 
+var domainDatabaseTypes = []string{
+	"GeoIP2-Domain",
+}
+
 func (r *DomainReader) Lookup(ip net.IP) (string, error) {
-	offset, err := r.getOffset(ip)
+	result, _, err := r.LookupWithPrefix(ip)
+	return result, err
+}
+
+func (r *DomainReader) LookupWithPrefix(ip net.IP) (string, netip.Prefix, error) {
+	offset, prefix, err := r.getOffsetWithPrefix(ip)
 	if err != nil {
-		return "", err
+		return "", netip.Prefix{}, err
 	}
+	result, err := r.decode(offset, prefix)
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	return result, prefix, nil
+}
+
+func (r *DomainReader) LookupAddr(addr netip.Addr) (string, error) {
+	result, _, err := r.LookupAddrWithPrefix(addr)
+	return result, err
+}
+
+func (r *DomainReader) LookupAddrWithPrefix(addr netip.Addr) (string, netip.Prefix, error) {
+	offset, prefix, err := r.getAddrOffsetWithPrefix(addr)
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	result, err := r.decode(offset, prefix)
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	return result, prefix, nil
+}
+
+func (r *DomainReader) decode(offset uint, _ netip.Prefix) (string, error) {
 	dataType, size, offset, err := readControl(r.decoderBuffer, offset)
 	if err != nil {
 		return "", err
@@ -23,7 +56,7 @@ func (r *DomainReader) Lookup(ip net.IP) (string, error) {
 	result := &Domain{}
 	switch dataType {
 	case dataTypeMap:
-		_, err = readDomainMap(result, r.decoderBuffer, size, offset)
+		_, err = readDomainMap(result, r.decoderBuffer, size, offset, r.options)
 		if err != nil {
 			return "", err
 		}
@@ -37,35 +70,70 @@ func (r *DomainReader) Lookup(ip net.IP) (string, error) {
 			return "", err
 		}
 		if dataType != dataTypeMap {
-			return "", errors.New("invalid Domain pointer type: " + strconv.Itoa(int(dataType)))
+			return "", newUnexpectedTypeError("Domain", dataTypeMap, dataType)
 		}
-		_, err = readDomainMap(result, r.decoderBuffer, size, offset)
+		_, err = readDomainMap(result, r.decoderBuffer, size, offset, r.options)
 		if err != nil {
 			return "", err
 		}
 	default:
-		return "", errors.New("invalid Domain type: " + strconv.Itoa(int(dataType)))
+		return "", newUnexpectedTypeError("Domain", dataTypeMap, dataType)
 	}
 	return result.Domain, nil
 }
 
-func NewDomainReader(buffer []byte) (*DomainReader, error) {
-	reader, err := newReader(buffer)
+func (r *DomainReader) Networks() *Networks[string] {
+	return newNetworks(r.reader, r.decode)
+}
+
+func (r *DomainReader) NetworksWithin(prefix netip.Prefix) *Networks[string] {
+	return newNetworksWithin(r.reader, prefix, r.decode)
+}
+
+// Domain returns a DomainReader sharing the database of r, which must be one of the
+// Domain database types unless r was opened WithoutDatabaseTypeCheck.
+func (r *Reader) Domain() (*DomainReader, error) {
+	err := r.checkDatabaseType("Domain", domainDatabaseTypes)
 	if err != nil {
 		return nil, err
 	}
-	if reader.metadata.DatabaseType != "GeoIP2-Domain" {
-		return nil, errors.New("wrong MaxMind DB Domain type: " + reader.metadata.DatabaseType)
+	return &DomainReader{
+		reader: r.reader,
+	}, nil
+}
+
+func NewDomainReader(buffer []byte, opts ...Option) (*DomainReader, error) {
+	reader, err := newReader(buffer, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = reader.checkDatabaseType("Domain", domainDatabaseTypes)
+	if err != nil {
+		return nil, err
 	}
 	return &DomainReader{
 		reader: reader,
 	}, nil
 }
 
-func NewDomainReaderFromFile(filename string) (*DomainReader, error) {
+func NewDomainReaderFromFile(filename string, opts ...Option) (*DomainReader, error) {
 	buffer, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	return NewDomainReader(buffer)
+	return NewDomainReader(buffer, opts...)
+}
+
+func NewDomainReaderFromFileMmap(filename string, opts ...Option) (*DomainReader, error) {
+	buffer, err := mmapFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewDomainReader(buffer, opts...)
+	if err != nil {
+		_ = munmap(buffer)
+		return nil, err
+	}
+	reader.mmap = buffer
+	return reader, nil
 }
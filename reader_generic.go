@@ -0,0 +1,111 @@
+package geoip2
+
+import (
+	"io/ioutil"
+	"net"
+	"net/netip"
+)
+
+// Reader reads databases of any type. City, Country and the other typed views
+// share its database, so closing the Reader or any of its views closes all of
+// them.
+type Reader struct {
+	*reader
+}
+
+// LookupOffset returns the data section offset of the record of ip, which can
+// be decoded with Decode, and the network of the record. Records shared by
+// several networks have the same offset.
+func (r *Reader) LookupOffset(ip net.IP) (uint, netip.Prefix, error) {
+	return r.getOffsetWithPrefix(ip)
+}
+
+func (r *Reader) LookupAddrOffset(addr netip.Addr) (uint, netip.Prefix, error) {
+	return r.getAddrOffsetWithPrefix(addr)
+}
+
+// Decode decodes the record at offset into result like Lookup.
+func (r *Reader) Decode(offset uint, result interface{}) error {
+	return r.decode(offset, result)
+}
+
+// Lookup decodes the record of ip into result, which must be a non-nil pointer.
+// Struct fields are matched to record keys by their maxminddb tag, or by their
+// name when the tag is missing. Keys without a matching field are skipped.
+// Empty interfaces receive map[string]interface{}, []interface{} and scalar
+// values of the corresponding Go type.
+func (r *Reader) Lookup(ip net.IP, result interface{}) error {
+	offset, _, err := r.getOffsetWithPrefix(ip)
+	if err != nil {
+		return err
+	}
+	return r.decode(offset, result)
+}
+
+func (r *Reader) LookupAddr(addr netip.Addr, result interface{}) error {
+	offset, _, err := r.getAddrOffsetWithPrefix(addr)
+	if err != nil {
+		return err
+	}
+	return r.decode(offset, result)
+}
+
+// Networks iterates over all networks of the database. Records are decoded into
+// map[string]interface{}, []interface{} and scalar values, or into a custom
+// type with Networks.Decode.
+func (r *Reader) Networks() *Networks[interface{}] {
+	return newNetworks(r.reader, r.decodeInterface)
+}
+
+func (r *Reader) NetworksWithin(prefix netip.Prefix) *Networks[interface{}] {
+	return newNetworksWithin(r.reader, prefix, r.decodeInterface)
+}
+
+func (r *Reader) decode(offset uint, result interface{}) error {
+	return decodeReflectInto(r.decoderBuffer, offset, result)
+}
+
+func (r *Reader) decodeInterface(offset uint, _ netip.Prefix) (interface{}, error) {
+	result, _, err := readInterface(r.decoderBuffer, offset, 0, newDataBudget(r.decoderBuffer))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func NewReader(buffer []byte, opts ...Option) (*Reader, error) {
+	reader, err := newReader(buffer, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		reader: reader,
+	}, nil
+}
+
+func NewReaderFromFile(filename string, opts ...Option) (*Reader, error) {
+	buffer, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(buffer, opts...)
+}
+
+// Open is a shorthand for NewReaderFromFile.
+func Open(filename string, opts ...Option) (*Reader, error) {
+	return NewReaderFromFile(filename, opts...)
+}
+
+func NewReaderFromFileMmap(filename string, opts ...Option) (*Reader, error) {
+	buffer, err := mmapFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewReader(buffer, opts...)
+	if err != nil {
+		_ = munmap(buffer)
+		return nil, err
+	}
+	reader.mmap = buffer
+	return reader, nil
+}
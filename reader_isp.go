@@ -1,71 +1,150 @@
 package geoip2
 
 import (
-	"errors"
 	"io/ioutil"
 	"net"
-	"strconv"
+	"net/netip"
 )
 
 type ISPReader struct {
 	*reader
 }
 
+var ispDatabaseTypes = []string{
+	"GeoIP2-ISP",
+}
+
 func (r *ISPReader) Lookup(ip net.IP) (*ISP, error) {
-	offset, err := r.getOffset(ip)
+	offset, prefix, err := r.getOffsetWithPrefix(ip)
 	if err != nil {
 		return nil, err
 	}
-	dataType, size, offset, err := readControl(r.decoderBuffer, offset)
+	return r.decode(offset, prefix)
+}
+
+func (r *ISPReader) LookupAddr(addr netip.Addr) (*ISP, error) {
+	offset, prefix, err := r.getAddrOffsetWithPrefix(addr)
 	if err != nil {
 		return nil, err
 	}
+	return r.decode(offset, prefix)
+}
+
+// LookupInto looks up ip and decodes its record into result.
+func (r *ISPReader) LookupInto(ip net.IP, result *ISP) error {
+	offset, prefix, err := r.getOffsetWithPrefix(ip)
+	if err != nil {
+		return err
+	}
+	return r.decodeInto(result, offset, prefix)
+}
+
+// LookupAddrInto is like LookupInto but takes a netip.Addr.
+func (r *ISPReader) LookupAddrInto(addr netip.Addr, result *ISP) error {
+	offset, prefix, err := r.getAddrOffsetWithPrefix(addr)
+	if err != nil {
+		return err
+	}
+	return r.decodeInto(result, offset, prefix)
+}
+
+func (r *ISPReader) decode(offset uint, prefix netip.Prefix) (*ISP, error) {
 	result := &ISP{}
+	err := r.decodeInto(result, offset, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *ISPReader) decodeInto(result *ISP, offset uint, prefix netip.Prefix) error {
+	dataType, size, offset, err := readControl(r.decoderBuffer, offset)
+	if err != nil {
+		return err
+	}
+	*result = ISP{
+		Prefix: prefix,
+	}
 	switch dataType {
 	case dataTypeMap:
-		_, err = readISPMap(result, r.decoderBuffer, size, offset)
+		_, err = readISPMap(result, r.decoderBuffer, size, offset, r.options)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	case dataTypePointer:
 		pointer, _, err := readPointer(r.decoderBuffer, size, offset)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		dataType, size, offset, err := readControl(r.decoderBuffer, pointer)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if dataType != dataTypeMap {
-			return nil, errors.New("invalid ISP pointer type: " + strconv.Itoa(int(dataType)))
+			return newUnexpectedTypeError("ISP", dataTypeMap, dataType)
 		}
-		_, err = readISPMap(result, r.decoderBuffer, size, offset)
+		_, err = readISPMap(result, r.decoderBuffer, size, offset, r.options)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	default:
-		return nil, errors.New("invalid ISP type: " + strconv.Itoa(int(dataType)))
+		return newUnexpectedTypeError("ISP", dataTypeMap, dataType)
 	}
-	return result, nil
+	return nil
+}
+
+func (r *ISPReader) Networks() *Networks[*ISP] {
+	return newNetworks(r.reader, r.decode)
+}
+
+func (r *ISPReader) NetworksWithin(prefix netip.Prefix) *Networks[*ISP] {
+	return newNetworksWithin(r.reader, prefix, r.decode)
 }
 
-func NewISPReader(buffer []byte) (*ISPReader, error) {
-	reader, err := newReader(buffer)
+// ISP returns a ISPReader sharing the database of r, which must be one of the
+// ISP database types unless r was opened WithoutDatabaseTypeCheck.
+func (r *Reader) ISP() (*ISPReader, error) {
+	err := r.checkDatabaseType("ISP", ispDatabaseTypes)
 	if err != nil {
 		return nil, err
 	}
-	if reader.metadata.DatabaseType != "GeoIP2-ISP" {
-		return nil, errors.New("wrong MaxMind DB ISP type: " + reader.metadata.DatabaseType)
+	return &ISPReader{
+		reader: r.reader,
+	}, nil
+}
+
+func NewISPReader(buffer []byte, opts ...Option) (*ISPReader, error) {
+	reader, err := newReader(buffer, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = reader.checkDatabaseType("ISP", ispDatabaseTypes)
+	if err != nil {
+		return nil, err
 	}
 	return &ISPReader{
 		reader: reader,
 	}, nil
 }
 
-func NewISPReaderFromFile(filename string) (*ISPReader, error) {
+func NewISPReaderFromFile(filename string, opts ...Option) (*ISPReader, error) {
 	buffer, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	return NewISPReader(buffer)
+	return NewISPReader(buffer, opts...)
+}
+
+func NewISPReaderFromFileMmap(filename string, opts ...Option) (*ISPReader, error) {
+	buffer, err := mmapFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewISPReader(buffer, opts...)
+	if err != nil {
+		_ = munmap(buffer)
+		return nil, err
+	}
+	reader.mmap = buffer
+	return reader, nil
 }
@@ -4,8 +4,18 @@
 package geoip2
 
 import (
+	"bytes"
+	"errors"
+	"math/big"
 	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestAnonymousIP(t *testing.T) {
@@ -56,9 +66,10 @@ func TestAnonymousIP(t *testing.T) {
 }
 
 func TestReaderZeroLength(t *testing.T) {
-	_, err := newReader([]byte{})
-	if err == nil {
-		t.Fatal()
+	_, err := newReader([]byte{}, nil)
+	invalidDatabase := &InvalidDatabaseError{}
+	if !errors.As(err, &invalidDatabase) {
+		t.Fatal(err)
 	}
 }
 
@@ -498,3 +509,1195 @@ func TestDBIPASN(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestNetworks(t *testing.T) {
+	reader, err := NewASNReaderFromFile("testdata/maxmind/test-data/GeoLite2-ASN-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	networks := reader.Networks()
+	for networks.Next() {
+		network := networks.Network()
+		if network.Addr().Is4In6() || netip.MustParsePrefix("2002::/16").Overlaps(network) {
+			t.Fatal(network)
+		}
+		record, err := networks.Record()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Network != network.String() {
+			t.Fatal(network)
+		}
+		if network.String() == "1.128.0.0/11" {
+			found = true
+			if record.AutonomousSystemNumber != 1221 {
+				t.Fatal()
+			}
+		}
+	}
+	if networks.Err() != nil {
+		t.Fatal(networks.Err())
+	}
+	if !found {
+		t.Fatal()
+	}
+}
+
+func TestNetworksWithin(t *testing.T) {
+	reader, err := NewASNReaderFromFile("testdata/maxmind/test-data/GeoLite2-ASN-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	networks := reader.NetworksWithin(netip.MustParsePrefix("1.128.0.0/16"))
+	if !networks.Next() {
+		t.Fatal(networks.Err())
+	}
+	if networks.Network().String() != "1.128.0.0/11" {
+		t.Fatal()
+	}
+	record, err := networks.Record()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.AutonomousSystemNumber != 1221 {
+		t.Fatal()
+	}
+	if networks.Next() {
+		t.Fatal()
+	}
+
+	within := netip.MustParsePrefix("2600::/8")
+	count := 0
+	networks = reader.NetworksWithin(within)
+	for networks.Next() {
+		if !within.Overlaps(networks.Network()) {
+			t.Fatal(networks.Network())
+		}
+		count++
+	}
+	if networks.Err() != nil {
+		t.Fatal(networks.Err())
+	}
+	if count == 0 {
+		t.Fatal()
+	}
+}
+
+func TestLookupAddr(t *testing.T) {
+	reader, err := NewCityReaderFromFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := reader.LookupAddr(netip.MustParseAddr("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.City.GeoNameID != 2643743 {
+		t.Fatal()
+	}
+	mapped, err := reader.LookupAddr(netip.MustParseAddr("::ffff:81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapped.City.GeoNameID != record.City.GeoNameID {
+		t.Fatal()
+	}
+
+	asnReader, err := NewASNReaderFromFile("testdata/maxmind/test-data/GeoLite2-ASN-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asn, err := asnReader.LookupAddr(netip.MustParseAddr("2600:6000::"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asn.AutonomousSystemNumber != 237 {
+		t.Fatal()
+	}
+	if asn.Network != "2600:6000::/20" {
+		t.Fatal()
+	}
+
+	connectionTypeReader, err := NewConnectionTypeReaderFromFile("testdata/maxmind/test-data/GeoIP2-Connection-Type-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := netip.MustParseAddr("1.0.1.0")
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = connectionTypeReader.LookupAddr(addr)
+	})
+	if allocs != 0 {
+		t.Fatal(allocs)
+	}
+}
+
+func TestLookupInto(t *testing.T) {
+	reader, err := NewCityReaderFromFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &CityResult{}
+	addr := netip.MustParseAddr("81.2.69.142")
+	err = reader.LookupAddrInto(addr, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.City.GeoNameID != 2643743 || result.City.Names["en"] != "London" {
+		t.Fatal()
+	}
+	if len(result.Subdivisions) != 1 || result.Subdivisions[0].ISOCode != "ENG" {
+		t.Fatal()
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = reader.LookupAddrInto(addr, result)
+	})
+	if allocs != 0 {
+		t.Fatal(allocs)
+	}
+	err = reader.LookupInto(net.ParseIP("2a02:ff80::"), result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Country.ISOCode != "DE" {
+		t.Fatal()
+	}
+	if result.City.GeoNameID != 0 || len(result.City.Names) != 0 || len(result.Subdivisions) != 0 || result.Postal.Code != "" {
+		t.Fatal()
+	}
+
+	asnReader, err := NewASNReaderFromFile("testdata/maxmind/test-data/GeoLite2-ASN-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asn := &ASN{}
+	addr = netip.MustParseAddr("2600:6000::")
+	err = asnReader.LookupAddrInto(addr, asn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asn.AutonomousSystemNumber != 237 || asn.Network != "2600:6000::/20" {
+		t.Fatal()
+	}
+	allocs = testing.AllocsPerRun(100, func() {
+		_ = asnReader.LookupAddrInto(addr, asn)
+	})
+	if allocs != 0 {
+		t.Fatal(allocs)
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	languages := []string{"en"}
+	option := WithLanguages(languages...)
+	languages[0] = "xx"
+	if newOptions([]Option{option}).languages[0] != "en" {
+		t.Fatal()
+	}
+	_, err := NewReader(newTestDatabase([]uint32{1, 1}, nil), WithLanguages("en"))
+	languageError := &UnknownLanguageError{}
+	if !errors.As(err, &languageError) || languageError.Language != "en" {
+		t.Fatal(err)
+	}
+
+	_, err = NewCityReaderFromFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb", WithLanguages("xx"))
+	if !errors.As(err, &languageError) || languageError.Language != "xx" {
+		t.Fatal(err)
+	}
+	reader, err := NewCityReaderFromFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb", WithLanguages("en", "de"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := reader.Lookup(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(record.City.Names) != 2 || record.City.Names["en"] != "London" || record.City.Names["de"] != "London" {
+		t.Fatal()
+	}
+	if len(record.Country.Names) != 2 || record.Country.Names["en"] != "United Kingdom" {
+		t.Fatal()
+	}
+	if len(record.Continent.Names) != 2 || len(record.Subdivisions[0].Names) != 2 {
+		t.Fatal()
+	}
+}
+
+func TestFields(t *testing.T) {
+	fields := []string{"country"}
+	option := WithFields(fields...)
+	fields[0] = "city"
+	if newOptions([]Option{option}).fields[0] != "country" {
+		t.Fatal()
+	}
+
+	reader, err := NewCityReaderFromFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb", WithFields("country", "location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := reader.Lookup(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Country.ISOCode != "GB" || record.Location.TimeZone != "Europe/London" {
+		t.Fatal()
+	}
+	if record.City.GeoNameID != 0 || record.Continent.Code != "" || len(record.Subdivisions) != 0 || record.RegisteredCountry.ISOCode != "" {
+		t.Fatal()
+	}
+
+	countryReader, err := NewCountryReaderFromFile("testdata/maxmind/test-data/GeoIP2-Country-Test.mmdb", WithFields("country"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	country, err := countryReader.Lookup(net.ParseIP("81.2.69.160"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if country.Country.ISOCode != "GB" || country.Continent.Code != "" {
+		t.Fatal()
+	}
+
+	_, err = NewCityReaderFromFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb", WithFields("contry"))
+	var fieldError *UnknownFieldError
+	if !errors.As(err, &fieldError) || fieldError.Field != "contry" {
+		t.Fatal(err)
+	}
+	_, err = NewCountryReaderFromFile("testdata/maxmind/test-data/GeoIP2-Country-Test.mmdb", WithFields("city"))
+	if err == nil {
+		t.Fatal()
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	cityReader, err := NewCityReaderFromFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	city, err := cityReader.Lookup(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !city.Prefix.IsValid() || !city.Prefix.Contains(netip.MustParseAddr("81.2.69.142")) {
+		t.Fatal(city.Prefix)
+	}
+
+	asnReader, err := NewASNReaderFromFile("testdata/maxmind/test-data/GeoLite2-ASN-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asn, err := asnReader.Lookup(net.ParseIP("1.128.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asn.Prefix != netip.MustParsePrefix("1.128.0.0/11") {
+		t.Fatal()
+	}
+
+	connectionTypeReader, err := NewConnectionTypeReaderFromFile("testdata/maxmind/test-data/GeoIP2-Connection-Type-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	connectionType, prefix, err := connectionTypeReader.LookupAddrWithPrefix(netip.MustParseAddr("1.0.1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if connectionType != "Cellular" {
+		t.Fatal()
+	}
+	if !prefix.Contains(netip.MustParseAddr("1.0.1.0")) {
+		t.Fatal(prefix)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	reader, err := NewCityReaderFromFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := reader.Metadata()
+	if metadata.DatabaseType != "GeoIP2-City" || metadata.IPVersion != 6 {
+		t.Fatal(metadata)
+	}
+	if metadata.BuildTime().Unix() != int64(metadata.BuildEpoch) || metadata.BuildTime().Location() != time.UTC {
+		t.Fatal()
+	}
+	if metadata.Age() <= 0 {
+		t.Fatal()
+	}
+	if reader.SearchTreeSize() != int(metadata.NodeCount)*int(metadata.RecordSize)/4 {
+		t.Fatal()
+	}
+	if reader.DataSectionSize() == 0 || reader.SearchTreeSize()+dataSectionSeparatorSize+reader.DataSectionSize() >= len(reader.buffer) {
+		t.Fatal()
+	}
+}
+
+func TestMmap(t *testing.T) {
+	reader, err := NewCityReaderFromFileMmap("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := reader.Lookup(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.City.GeoNameID != 2643743 {
+		t.Fatal()
+	}
+	err = reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadable(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "GeoIP2-City.mmdb")
+	copyFile := func(source string) {
+		buffer, err := os.ReadFile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filename, buffer, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	copyFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb")
+	reader, err := NewReloadableCityReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := reader.Lookup(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.City.GeoNameID != 2643743 {
+		t.Fatal()
+	}
+	reloaded, err := reader.ReloadIfModified()
+	if err != nil || reloaded {
+		t.Fatal(reloaded, err)
+	}
+	copyFile("testdata/maxmind/test-data/GeoIP2-Country-Test.mmdb")
+	err = reader.Reload()
+	if err == nil {
+		t.Fatal()
+	}
+	copyFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb")
+	err = reader.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err = reader.LookupAddr(netip.MustParseAddr("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.City.GeoNameID != 2643743 {
+		t.Fatal()
+	}
+	metadata, err := reader.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.DatabaseType != "GeoIP2-City" {
+		t.Fatal(metadata.DatabaseType)
+	}
+	searchTreeSize, err := reader.SearchTreeSize()
+	if err != nil || searchTreeSize != int(metadata.NodeCount)*int(metadata.RecordSize)/4 {
+		t.Fatal(searchTreeSize, err)
+	}
+	dataSectionSize, err := reader.DataSectionSize()
+	if err != nil || dataSectionSize == 0 {
+		t.Fatal(dataSectionSize, err)
+	}
+	err = reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = reader.Lookup(net.ParseIP("81.2.69.142"))
+	if !errors.Is(err, ErrReaderClosed) {
+		t.Fatal(err)
+	}
+	_, err = reader.Metadata()
+	if !errors.Is(err, ErrReaderClosed) {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadableConcurrent(t *testing.T) {
+	directory := t.TempDir()
+	filename := filepath.Join(directory, "GeoIP2-City.mmdb")
+	buffer, err := os.ReadFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both versions are written aside and renamed over the database, so
+	// every reload sees a complete file.
+	versions := make([]string, 2)
+	for i := range versions {
+		versions[i] = filepath.Join(directory, "GeoIP2-City-"+strconv.Itoa(i)+".mmdb")
+		err = os.WriteFile(versions[i], buffer, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = os.WriteFile(filename, buffer, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := NewReloadableCityReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var started, wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		started.Add(1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				if j == 1 {
+					started.Done()
+				}
+				record, err := reader.Lookup(net.ParseIP("81.2.69.142"))
+				if errors.Is(err, ErrReaderClosed) {
+					return
+				}
+				if err != nil {
+					t.Error(err)
+				} else if record.City.GeoNameID != 2643743 || record.City.Names["en"] != "London" {
+					t.Error(record.City)
+				}
+			}
+		}()
+	}
+
+	started.Wait()
+	modTime := time.Now()
+	for i := 0; i < 50; i++ {
+		version := versions[i%2]
+		err := os.Link(version, version+".next")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Rename(version+".next", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 0 {
+			err = reader.Reload()
+		} else {
+			modTime = modTime.Add(time.Second)
+			err = os.Chtimes(filename, modTime, modTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var reloaded bool
+			reloaded, err = reader.ReloadIfModified()
+			if err == nil && !reloaded {
+				t.Fatal()
+			}
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	err = reader.Reload()
+	if !errors.Is(err, ErrReaderClosed) {
+		t.Fatal(err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	for _, filename := range []string{
+		"GeoIP2-City-Test.mmdb",
+		"GeoIP2-Country-Test.mmdb",
+		"GeoIP2-ISP-Test.mmdb",
+		"GeoLite2-ASN-Test.mmdb",
+		"MaxMind-DB-test-decoder.mmdb",
+		"MaxMind-DB-test-ipv4-24.mmdb",
+		"MaxMind-DB-test-mixed-28.mmdb",
+		"MaxMind-DB-test-ipv6-32.mmdb",
+	} {
+		reader, err := NewReaderFromFile("testdata/maxmind/test-data/" + filename)
+		if err != nil {
+			t.Fatal(filename, err)
+		}
+		err = reader.Verify()
+		if err != nil {
+			t.Fatal(filename, err)
+		}
+	}
+	for _, filename := range []string{
+		"MaxMind-DB-test-broken-pointers-24.mmdb",
+		"MaxMind-DB-test-broken-search-tree-24.mmdb",
+	} {
+		reader, err := NewReaderFromFile("testdata/maxmind/test-data/" + filename)
+		if err != nil {
+			continue
+		}
+		err = reader.Verify()
+		if err == nil {
+			t.Fatal(filename)
+		}
+	}
+}
+
+func TestGenericReader(t *testing.T) {
+	reader, err := NewReaderFromFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record struct {
+		City struct {
+			GeoNameID uint              `maxminddb:"geoname_id"`
+			Names     map[string]string `maxminddb:"names"`
+		} `maxminddb:"city"`
+		Location *struct {
+			TimeZone string  `maxminddb:"time_zone"`
+			Latitude float64 `maxminddb:"latitude"`
+		} `maxminddb:"location"`
+		Subdivisions []struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"subdivisions"`
+	}
+	err = reader.Lookup(net.ParseIP("81.2.69.142"), &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.City.GeoNameID != 2643743 {
+		t.Fatal()
+	}
+	if record.City.Names["es"] != "Londres" {
+		t.Fatal()
+	}
+	if record.Location == nil || record.Location.TimeZone != "Europe/London" || record.Location.Latitude != 51.5142 {
+		t.Fatal()
+	}
+	if len(record.Subdivisions) != 1 || record.Subdivisions[0].ISOCode != "ENG" {
+		t.Fatal()
+	}
+
+	var wrongType struct {
+		City string `maxminddb:"city"`
+	}
+	err = reader.Lookup(net.ParseIP("81.2.69.142"), &wrongType)
+	if err == nil {
+		t.Fatal()
+	}
+	err = reader.Lookup(net.ParseIP("81.2.69.142"), wrongType)
+	if err == nil {
+		t.Fatal()
+	}
+}
+
+func TestGenericReaderUntyped(t *testing.T) {
+	reader, err := NewReaderFromFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record map[string]interface{}
+	err = reader.Lookup(net.ParseIP("81.2.69.142"), &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	city, ok := record["city"].(map[string]interface{})
+	if !ok {
+		t.Fatal()
+	}
+	if city["geoname_id"] != uint32(2643743) {
+		t.Fatal()
+	}
+	subdivisions, ok := record["subdivisions"].([]interface{})
+	if !ok || len(subdivisions) != 1 {
+		t.Fatal()
+	}
+	location, ok := record["location"].(map[string]interface{})
+	if !ok || location["time_zone"] != "Europe/London" {
+		t.Fatal()
+	}
+
+	count := 0
+	networks := reader.Networks()
+	for networks.Next() {
+		value, err := networks.Record()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := value.(map[string]interface{}); !ok {
+			t.Fatal(networks.Network())
+		}
+		count++
+	}
+	if networks.Err() != nil {
+		t.Fatal(networks.Err())
+	}
+	if count == 0 {
+		t.Fatal()
+	}
+}
+
+func TestReaderViews(t *testing.T) {
+	reader, err := Open("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.Metadata().DatabaseType != "GeoIP2-City" {
+		t.Fatal()
+	}
+	offset, prefix, err := reader.LookupOffset(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !prefix.IsValid() || !prefix.Contains(netip.MustParseAddr("81.2.69.142")) {
+		t.Fatal(prefix)
+	}
+	var record struct {
+		City struct {
+			GeoNameID uint `maxminddb:"geoname_id"`
+		} `maxminddb:"city"`
+	}
+	err = reader.Decode(offset, &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.City.GeoNameID != 2643743 {
+		t.Fatal()
+	}
+
+	city, err := reader.City()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cityResult, err := city.Lookup(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cityResult.City.GeoNameID != 2643743 {
+		t.Fatal()
+	}
+	_, err = reader.Country()
+	wrongType := &WrongDatabaseTypeError{}
+	if !errors.As(err, &wrongType) {
+		t.Fatal(err)
+	}
+
+	reader, err = Open("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb", WithoutDatabaseTypeCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	country, err := reader.Country()
+	if err != nil {
+		t.Fatal(err)
+	}
+	countryResult, err := country.Lookup(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if countryResult.Country.ISOCode != "GB" {
+		t.Fatal()
+	}
+	_, err = NewCountryReaderFromFile("testdata/maxmind/test-data/GeoIP2-City-Test.mmdb", WithoutDatabaseTypeCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecoderTypes(t *testing.T) {
+	reader, err := NewReaderFromFile("testdata/maxmind/test-data/MaxMind-DB-test-decoder.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record struct {
+		Array      []uint32 `maxminddb:"array"`
+		Boolean    bool     `maxminddb:"boolean"`
+		Bytes      []byte   `maxminddb:"bytes"`
+		Double     float64  `maxminddb:"double"`
+		Float      float32  `maxminddb:"float"`
+		Int32      int32    `maxminddb:"int32"`
+		Uint16     uint16   `maxminddb:"uint16"`
+		Uint32     uint32   `maxminddb:"uint32"`
+		Uint64     uint64   `maxminddb:"uint64"`
+		Uint128    *big.Int `maxminddb:"uint128"`
+		UTF8String string   `maxminddb:"utf8_string"`
+		Map        struct {
+			MapX struct {
+				ArrayX      []uint64 `maxminddb:"arrayX"`
+				UTF8StringX string   `maxminddb:"utf8_stringX"`
+			} `maxminddb:"mapX"`
+		} `maxminddb:"map"`
+	}
+	err = reader.Lookup(net.ParseIP("1.1.1.1"), &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(record.Array) != 3 || record.Array[0] != 1 || record.Array[2] != 3 {
+		t.Fatal()
+	}
+	if !record.Boolean {
+		t.Fatal()
+	}
+	if !bytes.Equal(record.Bytes, []byte{0x00, 0x00, 0x00, 0x2a}) {
+		t.Fatal()
+	}
+	if record.Double != 42.123456 {
+		t.Fatal()
+	}
+	if record.Float != 1.1 {
+		t.Fatal()
+	}
+	if record.Int32 != -268435456 {
+		t.Fatal()
+	}
+	if record.Uint16 != 100 {
+		t.Fatal()
+	}
+	if record.Uint32 != 268435456 {
+		t.Fatal()
+	}
+	if record.Uint64 != 1152921504606846976 {
+		t.Fatal()
+	}
+	if record.Uint128 == nil || record.Uint128.String() != "1329227995784915872903807060280344576" {
+		t.Fatal()
+	}
+	if record.UTF8String != "unicode! ☯ - ♫" {
+		t.Fatal()
+	}
+	if len(record.Map.MapX.ArrayX) != 3 || record.Map.MapX.UTF8StringX != "hello" {
+		t.Fatal()
+	}
+
+	var untyped map[string]interface{}
+	err = reader.Lookup(net.ParseIP("1.1.1.1"), &untyped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if untyped["int32"] != int32(-268435456) {
+		t.Fatal()
+	}
+	if untyped["float"] != float32(1.1) {
+		t.Fatal()
+	}
+	if untyped["uint64"] != uint64(1152921504606846976) {
+		t.Fatal()
+	}
+	if value, ok := untyped["uint128"].(*big.Int); !ok || value.String() != "1329227995784915872903807060280344576" {
+		t.Fatal()
+	}
+	if value, ok := untyped["bytes"].([]byte); !ok || !bytes.Equal(value, []byte{0x00, 0x00, 0x00, 0x2a}) {
+		t.Fatal()
+	}
+}
+
+func TestSkipValue(t *testing.T) {
+	// [true, {"a": [1, 2]}, <pointer to 0>, "end"]
+	buffer := []byte{
+		0x04, 0x04,
+		0x01, 0x07,
+		0xe1, 0x41, 'a', 0x02, 0x04, 0xa1, 0x01, 0xa1, 0x02,
+		0x20, 0x00,
+		0x43, 'e', 'n', 'd',
+	}
+	offset, err := skipValue(buffer, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != uint(len(buffer)) {
+		t.Fatal(offset)
+	}
+	offset, err = skipValue(buffer, 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != 13 {
+		t.Fatal(offset)
+	}
+	offset, err = skipValue(buffer, 13, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != 15 {
+		t.Fatal(offset)
+	}
+	_, err = skipValue(buffer[:len(buffer)-1], 0, 0)
+	if err == nil {
+		t.Fatal()
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	// {"foo": [1, {"a": "b"}], "geoname_id": 2643743}
+	buffer := []byte{
+		0xe2,
+		0x43, 'f', 'o', 'o',
+		0x02, 0x04, 0xa1, 0x01, 0xe1, 0x41, 'a', 0x41, 'b',
+		0x4a, 'g', 'e', 'o', 'n', 'a', 'm', 'e', '_', 'i', 'd',
+		0xc3, 0x28, 0x57, 0x1f,
+	}
+
+	city := City{}
+	offset, err := readCity(&city, buffer, 0, newOptions(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != uint(len(buffer)) {
+		t.Fatal()
+	}
+	if city.GeoNameID != 2643743 {
+		t.Fatal()
+	}
+
+	var unknown []string
+	options := newOptions([]Option{WithUnknownKeyHandler(func(section string, key string) error {
+		unknown = append(unknown, section+"."+key)
+		return nil
+	})})
+	_, err = readCity(&City{}, buffer, 0, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unknown) != 1 || unknown[0] != "city.foo" {
+		t.Fatal(unknown)
+	}
+
+	options = newOptions([]Option{WithUnknownKeyHandler(func(section string, key string) error {
+		return errors.New("unknown " + section + " key: " + key)
+	})})
+	_, err = readCity(&City{}, buffer, 0, options)
+	if err == nil {
+		t.Fatal()
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, err := NewCityReaderFromFile("testdata/maxmind/test-data/GeoIP2-Country-Test.mmdb")
+	wrongType := &WrongDatabaseTypeError{}
+	if !errors.As(err, &wrongType) {
+		t.Fatal(err)
+	}
+	if wrongType.Want != "City" || wrongType.Got != "GeoIP2-Country" {
+		t.Fatal(wrongType)
+	}
+
+	reader, err := NewReaderFromFile("testdata/maxmind/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record interface{}
+	err = reader.Lookup(net.ParseIP("2001:db8::1"), &record)
+	if !errors.Is(err, ErrIPv6InIPv4DB) {
+		t.Fatal(err)
+	}
+
+	// {"geoname_id": "2643743"}
+	buffer := []byte{
+		0xe1,
+		0x4a, 'g', 'e', 'o', 'n', 'a', 'm', 'e', '_', 'i', 'd',
+		0x47, '2', '6', '4', '3', '7', '4', '3',
+	}
+	_, err = readCity(&City{}, buffer, 0, newOptions(nil))
+	unexpectedType := &UnexpectedTypeError{}
+	if !errors.As(err, &unexpectedType) {
+		t.Fatal(err)
+	}
+	if unexpectedType.Field != "city.geoname_id" || unexpectedType.Expected != "uint32" || unexpectedType.Got != "string" {
+		t.Fatal(unexpectedType)
+	}
+	if err.Error() != "unexpected city.geoname_id type: expected uint32, got string" {
+		t.Fatal(err)
+	}
+
+	var result struct {
+		City struct {
+			GeoNameID uint32 `maxminddb:"geoname_id"`
+		} `maxminddb:"city"`
+	}
+	err = decodeReflectInto(append([]byte{0xe1, 0x44, 'c', 'i', 't', 'y'}, buffer...), 0, &result)
+	if !errors.As(err, &unexpectedType) {
+		t.Fatal(err)
+	}
+	if unexpectedType.Field != "city.geoname_id" {
+		t.Fatal(unexpectedType)
+	}
+
+	_, err = readCity(&City{}, buffer[:len(buffer)-1], 0, newOptions(nil))
+	invalidDatabase := &InvalidDatabaseError{}
+	if !errors.As(err, &invalidDatabase) {
+		t.Fatal(err)
+	}
+	if invalidDatabase.Offset != 13 {
+		t.Fatal(invalidDatabase)
+	}
+}
+
+func TestInvalidInput(t *testing.T) {
+	// {"geoname_id": 2643743}
+	data := []byte{0xe1, 0x4a, 'g', 'e', 'o', 'n', 'a', 'm', 'e', '_', 'i', 'd', 0xc3, 0x28, 0x57, 0x1f}
+	pointer := uint32(1 + dataSectionSeparatorSize)
+	reader, err := NewReader(newTestDatabase([]uint32{pointer, pointer}, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record interface{}
+	for _, ip := range []net.IP{nil, {1, 2, 3}, {1, 2, 3, 4, 5}} {
+		err = reader.Lookup(ip, &record)
+		if !errors.Is(err, ErrInvalidIP) {
+			t.Fatal(ip, err)
+		}
+		_, err = (&CityReader{reader.reader}).Lookup(ip)
+		if !errors.Is(err, ErrInvalidIP) {
+			t.Fatal(ip, err)
+		}
+		_, _, err = (&DomainReader{reader.reader}).LookupWithPrefix(ip)
+		if !errors.Is(err, ErrInvalidIP) {
+			t.Fatal(ip, err)
+		}
+	}
+	err = reader.LookupAddr(netip.Addr{}, &record)
+	if !errors.Is(err, ErrInvalidIP) {
+		t.Fatal(err)
+	}
+	networks := reader.NetworksWithin(netip.Prefix{})
+	if networks.Next() || !errors.Is(networks.Err(), ErrInvalidPrefix) {
+		t.Fatal(networks.Err())
+	}
+
+	var overflow struct {
+		GeoNameID uint16 `maxminddb:"geoname_id"`
+	}
+	err = reader.Lookup(net.ParseIP("1.1.1.1"), &overflow)
+	overflowError := &OverflowError{}
+	if !errors.As(err, &overflowError) {
+		t.Fatal(err)
+	}
+	if overflowError.Field != "geoname_id" || overflowError.Type != "uint16" || overflowError.Value != "2643743" {
+		t.Fatal(overflowError)
+	}
+	if err.Error() != "geoname_id value 2643743 overflows uint16" {
+		t.Fatal(err)
+	}
+	var intKeys map[int]uint32
+	err = reader.Lookup(net.ParseIP("1.1.1.1"), &intKeys)
+	unexpectedType := &UnexpectedTypeError{}
+	if !errors.As(err, &unexpectedType) {
+		t.Fatal(err)
+	}
+	if unexpectedType.Expected != "map[int]uint32" || unexpectedType.Got != "map" {
+		t.Fatal(unexpectedType)
+	}
+}
+
+// newTestDatabase builds an IPv4 database with 24 bit records. The records
+// hold the left and right record of every node in turn.
+func newTestDatabase(records []uint32, data []byte) []byte {
+	var buffer []byte
+	for _, record := range records {
+		buffer = append(buffer, byte(record>>16), byte(record>>8), byte(record))
+	}
+	buffer = append(buffer, make([]byte, dataSectionSeparatorSize)...)
+	buffer = append(buffer, data...)
+	buffer = append(buffer, metadataStartMarker...)
+	nodeCount := len(records) / 2
+	// {"node_count": nodeCount, "record_size": 24, "ip_version": 4, "database_type": "Test"}
+	return append(buffer,
+		0xe4,
+		0x4a, 'n', 'o', 'd', 'e', '_', 'c', 'o', 'u', 'n', 't',
+		0xc4, byte(nodeCount>>24), byte(nodeCount>>16), byte(nodeCount>>8), byte(nodeCount),
+		0x4b, 'r', 'e', 'c', 'o', 'r', 'd', '_', 's', 'i', 'z', 'e', 0xa1, 0x18,
+		0x4a, 'i', 'p', '_', 'v', 'e', 'r', 's', 'i', 'o', 'n', 0xa1, 0x04,
+		0x4d, 'd', 'a', 't', 'a', 'b', 'a', 's', 'e', '_', 't', 'y', 'p', 'e', 0x44, 'T', 'e', 's', 't',
+	)
+}
+
+func TestPointerOutsideDataSection(t *testing.T) {
+	// {"a": "b"}
+	data := []byte{0xe1, 0x41, 'a', 0x41, 'b'}
+	pointer := uint32(1 + dataSectionSeparatorSize)
+	metadataPointer := pointer + uint32(len(data)+len(metadataStartMarker))
+	reader, err := NewReader(newTestDatabase([]uint32{pointer, metadataPointer}, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record map[string]interface{}
+	err = reader.Lookup(net.ParseIP("1.1.1.1"), &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record["a"] != "b" {
+		t.Fatal(record)
+	}
+	err = reader.Lookup(net.ParseIP("200.0.0.1"), &record)
+	invalidDatabase := &InvalidDatabaseError{}
+	if !errors.As(err, &invalidDatabase) {
+		t.Fatal(err)
+	}
+	if invalidDatabase.Offset != uint(metadataPointer-pointer) || !strings.HasPrefix(invalidDatabase.Message, "the search tree points outside the data section") {
+		t.Fatal(invalidDatabase)
+	}
+	err = reader.Verify()
+	if !errors.As(err, &invalidDatabase) {
+		t.Fatal(err)
+	}
+}
+
+// newSharedPointerData builds 40 maps whose two keys both point to the next
+// map, so decoding the first map visits 2^40 values.
+func newSharedPointerData() []byte {
+	var data []byte
+	for i := 0; i < 40; i++ {
+		next := 9 * (i + 1)
+		pointer := []byte{0x20 | byte(next>>8), byte(next)}
+		data = append(data, 0xe2, 0x41, 'a')
+		data = append(data, pointer...)
+		data = append(data, 0x41, 'b')
+		data = append(data, pointer...)
+	}
+	return append(data, 0xe0)
+}
+
+func TestSharedSubtrees(t *testing.T) {
+	// Both records of every node point to the next node, the last node is
+	// empty: 2^32 paths without a network.
+	records := make([]uint32, 64)
+	for i := range records {
+		records[i] = uint32(i/2 + 1)
+	}
+	reader, err := NewReader(newTestDatabase(records, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	networks := reader.Networks()
+	if networks.Next() || networks.Err() != nil {
+		t.Fatal(networks.Network(), networks.Err())
+	}
+	networks = reader.NetworksWithin(netip.MustParsePrefix("10.0.0.0/8"))
+	if networks.Next() || networks.Err() != nil {
+		t.Fatal(networks.Network(), networks.Err())
+	}
+}
+
+func TestSharedPointers(t *testing.T) {
+	pointer := uint32(1 + dataSectionSeparatorSize)
+	reader, err := NewReader(newTestDatabase([]uint32{pointer, pointer}, newSharedPointerData()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var untyped interface{}
+	err = reader.Lookup(net.ParseIP("1.1.1.1"), &untyped)
+	invalidDatabase := &InvalidDatabaseError{}
+	if !errors.As(err, &invalidDatabase) {
+		t.Fatal(err)
+	}
+	var typed map[string]map[string]interface{}
+	err = reader.Lookup(net.ParseIP("1.1.1.1"), &typed)
+	if !errors.As(err, &invalidDatabase) {
+		t.Fatal(err)
+	}
+}
+
+func addFuzzSeeds(f *testing.F, add func(buffer []byte)) {
+	for _, filename := range []string{
+		"GeoIP2-Anonymous-IP-Test.mmdb",
+		"GeoIP2-City-Test.mmdb",
+		"GeoIP2-Connection-Type-Test.mmdb",
+		"GeoIP2-Country-Test.mmdb",
+		"GeoIP2-Domain-Test.mmdb",
+		"GeoIP2-ISP-Test.mmdb",
+		"GeoLite2-ASN-Test.mmdb",
+		"MaxMind-DB-test-decoder.mmdb",
+		"MaxMind-DB-test-ipv4-24.mmdb",
+	} {
+		buffer, err := os.ReadFile("testdata/maxmind/test-data/" + filename)
+		if err != nil {
+			continue
+		}
+		add(buffer)
+	}
+	// {"city": {"geoname_id": 2643743}, "traits": {"is_anycast": true}}
+	data := []byte{
+		0xe2,
+		0x44, 'c', 'i', 't', 'y',
+		0xe1, 0x4a, 'g', 'e', 'o', 'n', 'a', 'm', 'e', '_', 'i', 'd', 0xc3, 0x28, 0x57, 0x1f,
+		0x46, 't', 'r', 'a', 'i', 't', 's',
+		0xe1, 0x4a, 'i', 's', '_', 'a', 'n', 'y', 'c', 'a', 's', 't', 0x01, 0x07,
+	}
+	pointer := uint32(1 + dataSectionSeparatorSize)
+	add(newTestDatabase([]uint32{pointer, 1}, data))
+	add(newTestDatabase([]uint32{pointer, pointer + uint32(len(data))}, data))
+	add(newTestDatabase([]uint32{pointer, pointer}, newSharedPointerData()))
+	add([]byte{})
+}
+
+func FuzzNewReader(f *testing.F) {
+	addFuzzSeeds(f, func(buffer []byte) {
+		f.Add(buffer)
+	})
+	f.Fuzz(func(t *testing.T, buffer []byte) {
+		reader, err := newReader(buffer, nil)
+		if err != nil {
+			return
+		}
+		_ = reader.Verify()
+		networks := (&Reader{reader}).Networks()
+		for i := 0; i < 100 && networks.Next(); i++ {
+			_, _ = networks.Record()
+		}
+	})
+}
+
+func FuzzLookup(f *testing.F) {
+	addFuzzSeeds(f, func(buffer []byte) {
+		f.Add(buffer, []byte(net.ParseIP("81.2.69.142")))
+		f.Add(buffer, []byte(net.ParseIP("2a02:ff80::")))
+		f.Add(buffer, []byte(net.ParseIP("200.0.0.1").To4()))
+	})
+	f.Fuzz(func(t *testing.T, buffer []byte, ip []byte) {
+		reader, err := newReader(buffer, nil)
+		if err != nil {
+			return
+		}
+		_, _ = (&CityReader{reader}).Lookup(ip)
+		_, _ = (&CountryReader{reader}).Lookup(ip)
+		_, _ = (&ISPReader{reader}).Lookup(ip)
+		_, _ = (&ASNReader{reader}).Lookup(ip)
+		_, _ = (&AnonymousIPReader{reader}).Lookup(ip)
+		_, _ = (&ConnectionTypeReader{reader}).Lookup(ip)
+		_, _ = (&DomainReader{reader}).Lookup(ip)
+		var result interface{}
+		_ = (&Reader{reader}).Lookup(ip, &result)
+
+		addr, _ := netip.AddrFromSlice(ip)
+		_, _ = (&CityReader{reader}).LookupAddr(addr)
+		_, _ = (&CountryReader{reader}).LookupAddr(addr)
+		_, _ = (&ISPReader{reader}).LookupAddr(addr)
+		_, _ = (&ASNReader{reader}).LookupAddr(addr)
+		_, _ = (&AnonymousIPReader{reader}).LookupAddr(addr)
+		_, _ = (&ConnectionTypeReader{reader}).LookupAddr(addr)
+		_, _ = (&DomainReader{reader}).LookupAddr(addr)
+		_ = (&Reader{reader}).LookupAddr(addr, &result)
+
+		city := &CityResult{}
+		_ = (&CityReader{reader}).LookupInto(ip, city)
+		_ = (&CityReader{reader}).LookupAddrInto(addr, city)
+		country := &CountryResult{}
+		_ = (&CountryReader{reader}).LookupInto(ip, country)
+		_ = (&CountryReader{reader}).LookupAddrInto(addr, country)
+		_ = (&ISPReader{reader}).LookupInto(ip, &ISP{})
+		_ = (&ASNReader{reader}).LookupAddrInto(addr, &ASN{})
+		_ = (&AnonymousIPReader{reader}).LookupInto(ip, &AnonymousIP{})
+	})
+}
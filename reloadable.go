@@ -0,0 +1,206 @@
+package geoip2
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type reloadableReader interface {
+	Close() error
+	Metadata() *Metadata
+	SearchTreeSize() int
+	DataSectionSize() int
+}
+
+// reloadableHandle counts the lookups using a reader plus one reference held
+// while the reader is current. The reader is closed when the count drops to
+// zero and is never used again.
+type reloadableHandle[T reloadableReader] struct {
+	reader T
+	refs   int64
+	closed bool
+}
+
+func (h *reloadableHandle[T]) acquire() bool {
+	for {
+		refs := atomic.LoadInt64(&h.refs)
+		if refs == 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&h.refs, refs, refs+1) {
+			return true
+		}
+	}
+}
+
+func (h *reloadableHandle[T]) release() {
+	if atomic.AddInt64(&h.refs, -1) == 0 {
+		_ = h.reader.Close()
+	}
+}
+
+// reloadable opens its databases onto the heap: results hold strings pointing
+// into the database buffer, so unmapping a replaced database would invalidate
+// results callers kept.
+type reloadable[T reloadableReader] struct {
+	filename string
+	open     func(filename string) (T, error)
+	current  atomic.Value // *reloadableHandle[T]
+	mutex    sync.Mutex
+	modTime  time.Time
+	size     int64
+}
+
+func newReloadable[T reloadableReader](filename string, open func(filename string) (T, error)) (*reloadable[T], error) {
+	r := &reloadable[T]{
+		filename: filename,
+		open:     open,
+	}
+	err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *reloadable[T]) acquire() (*reloadableHandle[T], error) {
+	for {
+		handle := r.current.Load().(*reloadableHandle[T])
+		if handle.closed {
+			return nil, ErrReaderClosed
+		}
+		if handle.acquire() {
+			return handle, nil
+		}
+	}
+}
+
+// Metadata returns a copy of the metadata of the current database.
+func (r *reloadable[T]) Metadata() (*Metadata, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer handle.release()
+	metadata := *handle.reader.Metadata()
+	return &metadata, nil
+}
+
+// SearchTreeSize returns the size of the search tree of the current database
+// in bytes.
+func (r *reloadable[T]) SearchTreeSize() (int, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return 0, err
+	}
+	defer handle.release()
+	return handle.reader.SearchTreeSize(), nil
+}
+
+// DataSectionSize returns the size of the data section of the current database
+// in bytes.
+func (r *reloadable[T]) DataSectionSize() (int, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return 0, err
+	}
+	defer handle.release()
+	return handle.reader.DataSectionSize(), nil
+}
+
+// Reload re-opens the database file and swaps it in once it has been
+// validated. Lookups in flight keep using the previous database, which is
+// closed when the last of them finishes. Reloadable readers read databases
+// onto the heap and are never memory-mapped, so results obtained before a
+// reload stay valid.
+func (r *reloadable[T]) Reload() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stat, err := os.Stat(r.filename)
+	if err != nil {
+		return err
+	}
+	return r.reload(stat)
+}
+
+// ReloadIfModified reloads the database file if its modification time or size
+// changed since the last load. It reports whether the database was reloaded.
+func (r *reloadable[T]) ReloadIfModified() (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stat, err := os.Stat(r.filename)
+	if err != nil {
+		return false, err
+	}
+	if stat.ModTime().Equal(r.modTime) && stat.Size() == r.size {
+		return false, nil
+	}
+	err = r.reload(stat)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *reloadable[T]) reload(stat os.FileInfo) error {
+	previous, _ := r.current.Load().(*reloadableHandle[T])
+	if previous != nil && previous.closed {
+		return ErrReaderClosed
+	}
+	reader, err := r.open(r.filename)
+	if err != nil {
+		return err
+	}
+	if previous != nil && reader.Metadata().DatabaseType != previous.reader.Metadata().DatabaseType {
+		err = newWrongDatabaseTypeError(previous.reader.Metadata().DatabaseType, reader.Metadata().DatabaseType)
+		_ = reader.Close()
+		return err
+	}
+	r.modTime = stat.ModTime()
+	r.size = stat.Size()
+	r.current.Store(&reloadableHandle[T]{
+		reader: reader,
+		refs:   1,
+	})
+	if previous != nil {
+		previous.release()
+	}
+	return nil
+}
+
+// Watch calls ReloadIfModified every interval until ctx is done. Errors are
+// passed to onError, if set, and the current database is kept.
+func (r *reloadable[T]) Watch(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := r.ReloadIfModified()
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Close closes the database once the lookups in flight finish. Lookups and
+// reloads fail afterwards.
+func (r *reloadable[T]) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	previous := r.current.Load().(*reloadableHandle[T])
+	if previous.closed {
+		return nil
+	}
+	r.current.Store(&reloadableHandle[T]{
+		closed: true,
+	})
+	previous.release()
+	return nil
+}
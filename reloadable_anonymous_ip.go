@@ -0,0 +1,61 @@
+package geoip2
+
+import (
+	"net"
+	"net/netip"
+)
+
+// ReloadableAnonymousIPReader is an AnonymousIPReader whose database file can be
+// reloaded while lookups are running.
+type ReloadableAnonymousIPReader struct {
+	*reloadable[*AnonymousIPReader]
+}
+
+func (r *ReloadableAnonymousIPReader) Lookup(ip net.IP) (*AnonymousIP, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer handle.release()
+	return handle.reader.Lookup(ip)
+}
+
+func (r *ReloadableAnonymousIPReader) LookupAddr(addr netip.Addr) (*AnonymousIP, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddr(addr)
+}
+
+func (r *ReloadableAnonymousIPReader) LookupInto(ip net.IP, result *AnonymousIP) error {
+	handle, err := r.acquire()
+	if err != nil {
+		return err
+	}
+	defer handle.release()
+	return handle.reader.LookupInto(ip, result)
+}
+
+func (r *ReloadableAnonymousIPReader) LookupAddrInto(addr netip.Addr, result *AnonymousIP) error {
+	handle, err := r.acquire()
+	if err != nil {
+		return err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddrInto(addr, result)
+}
+
+// NewReloadableAnonymousIPReader opens filename like NewAnonymousIPReaderFromFile.
+func NewReloadableAnonymousIPReader(filename string, opts ...Option) (*ReloadableAnonymousIPReader, error) {
+	reloadable, err := newReloadable(filename, func(filename string) (*AnonymousIPReader, error) {
+		return NewAnonymousIPReaderFromFile(filename, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ReloadableAnonymousIPReader{
+		reloadable: reloadable,
+	}, nil
+}
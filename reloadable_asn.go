@@ -0,0 +1,61 @@
+package geoip2
+
+import (
+	"net"
+	"net/netip"
+)
+
+// ReloadableASNReader is an ASNReader whose database file can be
+// reloaded while lookups are running.
+type ReloadableASNReader struct {
+	*reloadable[*ASNReader]
+}
+
+func (r *ReloadableASNReader) Lookup(ip net.IP) (*ASN, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer handle.release()
+	return handle.reader.Lookup(ip)
+}
+
+func (r *ReloadableASNReader) LookupAddr(addr netip.Addr) (*ASN, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddr(addr)
+}
+
+func (r *ReloadableASNReader) LookupInto(ip net.IP, result *ASN) error {
+	handle, err := r.acquire()
+	if err != nil {
+		return err
+	}
+	defer handle.release()
+	return handle.reader.LookupInto(ip, result)
+}
+
+func (r *ReloadableASNReader) LookupAddrInto(addr netip.Addr, result *ASN) error {
+	handle, err := r.acquire()
+	if err != nil {
+		return err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddrInto(addr, result)
+}
+
+// NewReloadableASNReader opens filename like NewASNReaderFromFile.
+func NewReloadableASNReader(filename string, opts ...Option) (*ReloadableASNReader, error) {
+	reloadable, err := newReloadable(filename, func(filename string) (*ASNReader, error) {
+		return NewASNReaderFromFile(filename, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ReloadableASNReader{
+		reloadable: reloadable,
+	}, nil
+}
@@ -0,0 +1,66 @@
+package geoip2
+
+import (
+	"net"
+	"net/netip"
+)
+
+// ReloadableCityReader is a CityReader whose database file can be
+// reloaded while lookups are running.
+type ReloadableCityReader struct {
+	*reloadable[*CityReader]
+}
+
+func (r *ReloadableCityReader) Lookup(ip net.IP) (*CityResult, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer handle.release()
+	return handle.reader.Lookup(ip)
+}
+
+func (r *ReloadableCityReader) LookupAddr(addr netip.Addr) (*CityResult, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddr(addr)
+}
+
+func (r *ReloadableCityReader) LookupInto(ip net.IP, result *CityResult) error {
+	handle, err := r.acquire()
+	if err != nil {
+		return err
+	}
+	defer handle.release()
+	return handle.reader.LookupInto(ip, result)
+}
+
+func (r *ReloadableCityReader) LookupAddrInto(addr netip.Addr, result *CityResult) error {
+	handle, err := r.acquire()
+	if err != nil {
+		return err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddrInto(addr, result)
+}
+
+// NewReloadableCityReader opens filename like NewCityReaderFromFile.
+func NewReloadableCityReader(filename string, opts ...Option) (*ReloadableCityReader, error) {
+	reloadable, err := newReloadable(filename, func(filename string) (*CityReader, error) {
+		return NewCityReaderFromFile(filename, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ReloadableCityReader{
+		reloadable: reloadable,
+	}, nil
+}
+
+// NewReloadableEnterpriseReader opens filename like NewEnterpriseReaderFromFile.
+func NewReloadableEnterpriseReader(filename string, opts ...Option) (*ReloadableCityReader, error) {
+	return NewReloadableCityReader(filename, opts...)
+}
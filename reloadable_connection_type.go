@@ -0,0 +1,61 @@
+package geoip2
+
+import (
+	"net"
+	"net/netip"
+)
+
+// ReloadableConnectionTypeReader is a ConnectionTypeReader whose database file can be
+// reloaded while lookups are running.
+type ReloadableConnectionTypeReader struct {
+	*reloadable[*ConnectionTypeReader]
+}
+
+func (r *ReloadableConnectionTypeReader) Lookup(ip net.IP) (string, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer handle.release()
+	return handle.reader.Lookup(ip)
+}
+
+func (r *ReloadableConnectionTypeReader) LookupAddr(addr netip.Addr) (string, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddr(addr)
+}
+
+func (r *ReloadableConnectionTypeReader) LookupWithPrefix(ip net.IP) (string, netip.Prefix, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	defer handle.release()
+	return handle.reader.LookupWithPrefix(ip)
+}
+
+func (r *ReloadableConnectionTypeReader) LookupAddrWithPrefix(addr netip.Addr) (string, netip.Prefix, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddrWithPrefix(addr)
+}
+
+// NewReloadableConnectionTypeReader opens filename like NewConnectionTypeReaderFromFile.
+func NewReloadableConnectionTypeReader(filename string, opts ...Option) (*ReloadableConnectionTypeReader, error) {
+	reloadable, err := newReloadable(filename, func(filename string) (*ConnectionTypeReader, error) {
+		return NewConnectionTypeReaderFromFile(filename, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ReloadableConnectionTypeReader{
+		reloadable: reloadable,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package geoip2
+
+import (
+	"net"
+	"net/netip"
+)
+
+// ReloadableCountryReader is a CountryReader whose database file can be
+// reloaded while lookups are running.
+type ReloadableCountryReader struct {
+	*reloadable[*CountryReader]
+}
+
+func (r *ReloadableCountryReader) Lookup(ip net.IP) (*CountryResult, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer handle.release()
+	return handle.reader.Lookup(ip)
+}
+
+func (r *ReloadableCountryReader) LookupAddr(addr netip.Addr) (*CountryResult, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddr(addr)
+}
+
+func (r *ReloadableCountryReader) LookupInto(ip net.IP, result *CountryResult) error {
+	handle, err := r.acquire()
+	if err != nil {
+		return err
+	}
+	defer handle.release()
+	return handle.reader.LookupInto(ip, result)
+}
+
+func (r *ReloadableCountryReader) LookupAddrInto(addr netip.Addr, result *CountryResult) error {
+	handle, err := r.acquire()
+	if err != nil {
+		return err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddrInto(addr, result)
+}
+
+// NewReloadableCountryReader opens filename like NewCountryReaderFromFile.
+func NewReloadableCountryReader(filename string, opts ...Option) (*ReloadableCountryReader, error) {
+	reloadable, err := newReloadable(filename, func(filename string) (*CountryReader, error) {
+		return NewCountryReaderFromFile(filename, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ReloadableCountryReader{
+		reloadable: reloadable,
+	}, nil
+}
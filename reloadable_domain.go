@@ -0,0 +1,61 @@
+package geoip2
+
+import (
+	"net"
+	"net/netip"
+)
+
+// ReloadableDomainReader is a DomainReader whose database file can be
+// reloaded while lookups are running.
+type ReloadableDomainReader struct {
+	*reloadable[*DomainReader]
+}
+
+func (r *ReloadableDomainReader) Lookup(ip net.IP) (string, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer handle.release()
+	return handle.reader.Lookup(ip)
+}
+
+func (r *ReloadableDomainReader) LookupAddr(addr netip.Addr) (string, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddr(addr)
+}
+
+func (r *ReloadableDomainReader) LookupWithPrefix(ip net.IP) (string, netip.Prefix, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	defer handle.release()
+	return handle.reader.LookupWithPrefix(ip)
+}
+
+func (r *ReloadableDomainReader) LookupAddrWithPrefix(addr netip.Addr) (string, netip.Prefix, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return "", netip.Prefix{}, err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddrWithPrefix(addr)
+}
+
+// NewReloadableDomainReader opens filename like NewDomainReaderFromFile.
+func NewReloadableDomainReader(filename string, opts ...Option) (*ReloadableDomainReader, error) {
+	reloadable, err := newReloadable(filename, func(filename string) (*DomainReader, error) {
+		return NewDomainReaderFromFile(filename, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ReloadableDomainReader{
+		reloadable: reloadable,
+	}, nil
+}
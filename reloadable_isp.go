@@ -0,0 +1,61 @@
+package geoip2
+
+import (
+	"net"
+	"net/netip"
+)
+
+// ReloadableISPReader is an ISPReader whose database file can be
+// reloaded while lookups are running.
+type ReloadableISPReader struct {
+	*reloadable[*ISPReader]
+}
+
+func (r *ReloadableISPReader) Lookup(ip net.IP) (*ISP, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer handle.release()
+	return handle.reader.Lookup(ip)
+}
+
+func (r *ReloadableISPReader) LookupAddr(addr netip.Addr) (*ISP, error) {
+	handle, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddr(addr)
+}
+
+func (r *ReloadableISPReader) LookupInto(ip net.IP, result *ISP) error {
+	handle, err := r.acquire()
+	if err != nil {
+		return err
+	}
+	defer handle.release()
+	return handle.reader.LookupInto(ip, result)
+}
+
+func (r *ReloadableISPReader) LookupAddrInto(addr netip.Addr, result *ISP) error {
+	handle, err := r.acquire()
+	if err != nil {
+		return err
+	}
+	defer handle.release()
+	return handle.reader.LookupAddrInto(addr, result)
+}
+
+// NewReloadableISPReader opens filename like NewISPReaderFromFile.
+func NewReloadableISPReader(filename string, opts ...Option) (*ReloadableISPReader, error) {
+	reloadable, err := newReloadable(filename, func(filename string) (*ISPReader, error) {
+		return NewISPReaderFromFile(filename, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ReloadableISPReader{
+		reloadable: reloadable,
+	}, nil
+}
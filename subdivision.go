@@ -1,18 +1,13 @@
 package geoip2
 
-import (
-	"errors"
-	"strconv"
-)
-
-func readSubdivisions(buffer []byte, offset uint) ([]Subdivision, uint, error) {
+func readSubdivisions(subdivisions []Subdivision, buffer []byte, offset uint, options *options) ([]Subdivision, uint, error) {
 	dataType, size, offset, err := readControl(buffer, offset)
 	if err != nil {
 		return nil, 0, err
 	}
 	switch dataType {
 	case dataTypeSlice:
-		return readSubdivisionsSlice(buffer, size, offset)
+		return readSubdivisionsSlice(subdivisions, buffer, size, offset, options)
 	case dataTypePointer:
 		pointer, newOffset, err := readPointer(buffer, size, offset)
 		if err != nil {
@@ -23,23 +18,28 @@ func readSubdivisions(buffer []byte, offset uint) ([]Subdivision, uint, error) {
 			return nil, 0, err
 		}
 		if dataType != dataTypeSlice {
-			return nil, 0, errors.New("invalid subdivisions pointer type: " + strconv.Itoa(int(dataType)))
+			return nil, 0, newUnexpectedTypeError("subdivisions", dataTypeSlice, dataType)
 		}
-		subdivisions, _, err := readSubdivisionsSlice(buffer, size, offset)
+		subdivisions, _, err := readSubdivisionsSlice(subdivisions, buffer, size, offset, options)
 		if err != nil {
 			return nil, 0, err
 		}
 		return subdivisions, newOffset, nil
 	default:
-		return nil, 0, errors.New("invalid subdivisions type: " + strconv.Itoa(int(dataType)))
+		return nil, 0, newUnexpectedTypeError("subdivisions", dataTypeSlice, dataType)
 	}
 }
 
-func readSubdivisionsSlice(buffer []byte, subdivisionsSize uint, offset uint) ([]Subdivision, uint, error) {
+func readSubdivisionsSlice(subdivisions []Subdivision, buffer []byte, subdivisionsSize uint, offset uint, options *options) ([]Subdivision, uint, error) {
 	var err error
-	subdivisions := make([]Subdivision, subdivisionsSize)
+	if uint(cap(subdivisions)) < subdivisionsSize {
+		subdivisions = make([]Subdivision, subdivisionsSize)
+	} else {
+		subdivisions = subdivisions[:subdivisionsSize]
+	}
 	for i := uint(0); i < subdivisionsSize; i++ {
-		offset, err = readSubdivision(&subdivisions[i], buffer, offset)
+		subdivisions[i].reset()
+		offset, err = readSubdivision(&subdivisions[i], buffer, offset, options)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -47,14 +47,14 @@ func readSubdivisionsSlice(buffer []byte, subdivisionsSize uint, offset uint) ([
 	return subdivisions, offset, nil
 }
 
-func readSubdivision(subdivision *Subdivision, buffer []byte, offset uint) (uint, error) {
+func readSubdivision(subdivision *Subdivision, buffer []byte, offset uint, options *options) (uint, error) {
 	dataType, size, offset, err := readControl(buffer, offset)
 	if err != nil {
 		return 0, err
 	}
 	switch dataType {
 	case dataTypeMap:
-		return readSubdivisionMap(subdivision, buffer, size, offset)
+		return readSubdivisionMap(subdivision, buffer, size, offset, options)
 	case dataTypePointer:
 		pointer, newOffset, err := readPointer(buffer, size, offset)
 		if err != nil {
@@ -65,19 +65,19 @@ func readSubdivision(subdivision *Subdivision, buffer []byte, offset uint) (uint
 			return 0, err
 		}
 		if dataType != dataTypeMap {
-			return 0, errors.New("invalid subdivision pointer type: " + strconv.Itoa(int(dataType)))
+			return 0, newUnexpectedTypeError("subdivision", dataTypeMap, dataType)
 		}
-		_, err = readSubdivisionMap(subdivision, buffer, size, offset)
+		_, err = readSubdivisionMap(subdivision, buffer, size, offset, options)
 		if err != nil {
 			return 0, err
 		}
 		return newOffset, nil
 	default:
-		return 0, errors.New("invalid subdivision type: " + strconv.Itoa(int(dataType)))
+		return 0, newUnexpectedTypeError("subdivision", dataTypeMap, dataType)
 	}
 }
 
-func readSubdivisionMap(subdivision *Subdivision, buffer []byte, mapSize uint, offset uint) (uint, error) {
+func readSubdivisionMap(subdivision *Subdivision, buffer []byte, mapSize uint, offset uint, options *options) (uint, error) {
 	var key []byte
 	var err error
 	for i := uint(0); i < mapSize; i++ {
@@ -89,26 +89,37 @@ func readSubdivisionMap(subdivision *Subdivision, buffer []byte, mapSize uint, o
 		case "geoname_id":
 			subdivision.GeoNameID, offset, err = readUInt32(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "subdivisions", key)
 			}
 		case "iso_code":
 			subdivision.ISOCode, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "subdivisions", key)
 			}
 		case "names":
-			subdivision.Names, offset, err = readStringMap(buffer, offset)
+			subdivision.Names, offset, err = readStringMap(subdivision.Names, buffer, offset, options.languages)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "subdivisions", key)
 			}
 		case "confidence":
 			subdivision.Confidence, offset, err = readUInt16(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "subdivisions", key)
 			}
 		default:
-			return 0, errors.New("unknown subdivision key: " + string(key))
+			offset, err = skipUnknownKey(options, "subdivisions", key, buffer, offset)
+			if err != nil {
+				return 0, err
+			}
 		}
 	}
 	return offset, nil
 }
+
+func (subdivision *Subdivision) reset() {
+	names := subdivision.Names
+	clearStringMap(names)
+	*subdivision = Subdivision{
+		Names: names,
+	}
+}
@@ -1,18 +1,13 @@
 package geoip2
 
-import (
-	"errors"
-	"strconv"
-)
-
-func readTraits(traits *Traits, buffer []byte, offset uint) (uint, error) {
+func readTraits(traits *Traits, buffer []byte, offset uint, options *options) (uint, error) {
 	dataType, size, offset, err := readControl(buffer, offset)
 	if err != nil {
 		return 0, err
 	}
 	switch dataType {
 	case dataTypeMap:
-		return readTraitsMap(traits, buffer, size, offset)
+		return readTraitsMap(traits, buffer, size, offset, options)
 	case dataTypePointer:
 		pointer, newOffset, err := readPointer(buffer, size, offset)
 		if err != nil {
@@ -23,19 +18,19 @@ func readTraits(traits *Traits, buffer []byte, offset uint) (uint, error) {
 			return 0, err
 		}
 		if dataType != dataTypeMap {
-			return 0, errors.New("invalid traits pointer type: " + strconv.Itoa(int(dataType)))
+			return 0, newUnexpectedTypeError("traits", dataTypeMap, dataType)
 		}
-		_, err = readTraitsMap(traits, buffer, size, offset)
+		_, err = readTraitsMap(traits, buffer, size, offset, options)
 		if err != nil {
 			return 0, err
 		}
 		return newOffset, nil
 	default:
-		return 0, errors.New("invalid traits type: " + strconv.Itoa(int(dataType)))
+		return 0, newUnexpectedTypeError("traits", dataTypeMap, dataType)
 	}
 }
 
-func readTraitsMap(traits *Traits, buffer []byte, mapSize uint, offset uint) (uint, error) {
+func readTraitsMap(traits *Traits, buffer []byte, mapSize uint, offset uint, options *options) (uint, error) {
 	var key []byte
 	var err error
 	for i := uint(0); i < mapSize; i++ {
@@ -47,70 +42,78 @@ func readTraitsMap(traits *Traits, buffer []byte, mapSize uint, offset uint) (ui
 		case "is_anonymous_proxy":
 			traits.IsAnonymousProxy, offset, err = readBool(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "traits", key)
 			}
 		case "is_satellite_provider":
 			traits.IsSatelliteProvider, offset, err = readBool(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "traits", key)
 			}
 		case "is_legitimate_proxy":
 			traits.IsLegitimateProxy, offset, err = readBool(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "traits", key)
 			}
 		case "static_ip_score":
 			traits.StaticIPScore, offset, err = readFloat64(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "traits", key)
 			}
 		case "autonomous_system_number":
 			traits.AutonomousSystemNumber, offset, err = readUInt32(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "traits", key)
 			}
 		case "autonomous_system_organization":
 			traits.AutonomousSystemOrganization, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "traits", key)
 			}
 		case "isp":
 			traits.ISP, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "traits", key)
 			}
 		case "organization":
 			traits.Organization, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "traits", key)
 			}
 		case "connection_type":
 			traits.ConnectionType, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "traits", key)
 			}
 		case "domain":
 			traits.Domain, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "traits", key)
 			}
 		case "user_type":
 			traits.UserType, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "traits", key)
+			}
+		case "is_anycast":
+			traits.IsAnycast, offset, err = readBool(buffer, offset)
+			if err != nil {
+				return 0, withField(err, "traits", key)
 			}
 		case "mobile_country_code":
 			traits.MobileCountryCode, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "traits", key)
 			}
 		case "mobile_network_code":
 			traits.MobileNetworkCode, offset, err = readString(buffer, offset)
 			if err != nil {
-				return 0, err
+				return 0, withField(err, "traits", key)
 			}
 		default:
-			return 0, errors.New("unknown traits key: " + string(key))
+			offset, err = skipUnknownKey(options, "traits", key, buffer, offset)
+			if err != nil {
+				return 0, err
+			}
 		}
 	}
 	return offset, nil
@@ -1,5 +1,7 @@
 package geoip2
 
+import "net/netip"
+
 const (
 	dataTypeExtended           = 0
 	dataTypePointer            = 1
@@ -19,6 +21,9 @@ const (
 	dataTypeFloat32            = 15
 
 	dataSectionSeparatorSize = 16
+
+	maxDataDepth      = 512
+	minDataBudgetSize = 1 << 16
 )
 
 type Continent struct {
@@ -76,6 +81,7 @@ type Traits struct {
 	MobileNetworkCode            string  // Enterprise
 	IsAnonymousProxy             bool
 	IsSatelliteProvider          bool
+	IsAnycast                    bool
 }
 
 type CountryResult struct {
@@ -84,6 +90,7 @@ type CountryResult struct {
 	RegisteredCountry  Country
 	RepresentedCountry Country
 	Traits             Traits
+	Prefix             netip.Prefix
 }
 
 type CityResult struct {
@@ -96,6 +103,7 @@ type CityResult struct {
 	RegisteredCountry  Country
 	RepresentedCountry Country
 	Traits             Traits
+	Prefix             netip.Prefix
 }
 
 type ISP struct {
@@ -105,6 +113,7 @@ type ISP struct {
 	Organization                 string
 	MobileCountryCode            string
 	MobileNetworkCode            string
+	Prefix                       netip.Prefix
 }
 
 type ConnectionType struct {
@@ -118,12 +127,14 @@ type AnonymousIP struct {
 	IsPublicProxy      bool
 	IsTorExitNode      bool
 	IsResidentialProxy bool
+	Prefix             netip.Prefix
 }
 
 type ASN struct {
 	AutonomousSystemNumber       uint32
 	AutonomousSystemOrganization string
 	Network                      string
+	Prefix                       netip.Prefix
 }
 
 type Domain struct {
@@ -0,0 +1,231 @@
+// Package update downloads MaxMind databases using the GeoIP Update protocol.
+package update
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/IncSW/geoip2"
+)
+
+const DefaultHost = "https://updates.maxmind.com"
+
+type Client struct {
+	AccountID  int
+	LicenseKey string
+	Host       string       // DefaultHost if empty
+	HTTPClient *http.Client // http.DefaultClient if nil
+}
+
+func NewClient(accountID int, licenseKey string) *Client {
+	return &Client{
+		AccountID:  accountID,
+		LicenseKey: licenseKey,
+	}
+}
+
+type metadataResponse struct {
+	Databases []struct {
+		EditionID string `json:"edition_id"`
+		MD5       string `json:"md5"`
+		Date      string `json:"date"`
+	} `json:"databases"`
+}
+
+// Update installs the latest database of the edition as <editionID>.mmdb in
+// directory, unless the installed database is current. The new database is
+// validated by the reader of its type before it atomically replaces the
+// installed one. Update reports whether the database was replaced.
+func (c *Client) Update(ctx context.Context, directory string, editionID string) (bool, error) {
+	filename := filepath.Join(directory, editionID+".mmdb")
+	currentMD5, err := fileMD5(filename)
+	if err != nil {
+		return false, err
+	}
+	latestMD5, date, err := c.metadata(ctx, editionID)
+	if err != nil {
+		return false, err
+	}
+	if latestMD5 == currentMD5 {
+		return false, nil
+	}
+	return c.download(ctx, filename, editionID, date, latestMD5, currentMD5)
+}
+
+func (c *Client) metadata(ctx context.Context, editionID string) (string, string, error) {
+	response, err := c.get(ctx, "/geoip/updates/metadata?edition_id="+url.QueryEscape(editionID), "")
+	if err != nil {
+		return "", "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", "", statusError(response)
+	}
+	metadata := &metadataResponse{}
+	err = json.NewDecoder(response.Body).Decode(metadata)
+	if err != nil {
+		return "", "", err
+	}
+	for _, database := range metadata.Databases {
+		if database.EditionID == editionID {
+			return strings.ToLower(database.MD5), database.Date, nil
+		}
+	}
+	return "", "", errors.New("edition not found: " + editionID)
+}
+
+func (c *Client) download(ctx context.Context, filename string, editionID string, date string, latestMD5 string, currentMD5 string) (bool, error) {
+	path := "/geoip/databases/" + url.PathEscape(editionID) + "/download?date=" + url.QueryEscape(strings.ReplaceAll(date, "-", "")) + "&suffix=tar.gz"
+	response, err := c.get(ctx, path, currentMD5)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return false, nil
+	default:
+		return false, statusError(response)
+	}
+	file, err := os.CreateTemp(filepath.Dir(filename), "."+editionID+"-*.tmp")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(file.Name())
+	downloadedMD5, err := extract(file, response.Body)
+	if err == nil {
+		err = file.Chmod(0o644)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, err
+	}
+	if downloadedMD5 != latestMD5 {
+		return false, errors.New("MD5 mismatch of " + editionID + ": expected " + latestMD5 + ", got " + downloadedMD5)
+	}
+	err = validate(editionID, file.Name())
+	if err != nil {
+		return false, err
+	}
+	err = os.Rename(file.Name(), filename)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, ifNoneMatch string) (*http.Response, error) {
+	host := c.Host
+	if host == "" {
+		host = DefaultHost
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(host, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.SetBasicAuth(strconv.Itoa(c.AccountID), c.LicenseKey)
+	if ifNoneMatch != "" {
+		request.Header.Set("If-None-Match", `"`+ifNoneMatch+`"`)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(request)
+}
+
+func statusError(response *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 256))
+	message := "unexpected HTTP status: " + response.Status
+	if len(body) != 0 {
+		message += ": " + strings.TrimSpace(string(body))
+	}
+	return errors.New(message)
+}
+
+// extract writes the .mmdb file of a tar.gz archive to writer and returns its
+// MD5.
+func extract(writer io.Writer, reader io.Reader) (string, error) {
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return "", err
+	}
+	defer gzipReader.Close()
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return "", errors.New("no .mmdb file in archive")
+		}
+		if err != nil {
+			return "", err
+		}
+		if header.Typeflag != tar.TypeReg || !strings.HasSuffix(header.Name, ".mmdb") {
+			continue
+		}
+		hash := md5.New()
+		_, err = io.Copy(io.MultiWriter(writer, hash), tarReader)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+}
+
+func fileMD5(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer file.Close()
+	hash := md5.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func validate(editionID string, filename string) error {
+	var err error
+	switch {
+	case strings.HasSuffix(editionID, "-City"), strings.HasSuffix(editionID, "-Enterprise"):
+		_, err = geoip2.NewCityReaderFromFile(filename)
+	case strings.HasSuffix(editionID, "-Country"):
+		_, err = geoip2.NewCountryReaderFromFile(filename)
+	case strings.HasSuffix(editionID, "-ISP"):
+		_, err = geoip2.NewISPReaderFromFile(filename)
+	case strings.HasSuffix(editionID, "-ASN"):
+		_, err = geoip2.NewASNReaderFromFile(filename)
+	case strings.HasSuffix(editionID, "-Connection-Type"):
+		_, err = geoip2.NewConnectionTypeReaderFromFile(filename)
+	case strings.HasSuffix(editionID, "-Domain"):
+		_, err = geoip2.NewDomainReaderFromFile(filename)
+	case strings.HasSuffix(editionID, "-Anonymous-IP"):
+		_, err = geoip2.NewAnonymousIPReaderFromFile(filename)
+	default:
+		_, err = geoip2.NewReaderFromFile(filename)
+	}
+	return err
+}
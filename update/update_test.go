@@ -0,0 +1,155 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/IncSW/geoip2"
+	"github.com/IncSW/geoip2/writer"
+)
+
+func newTestDatabase(t *testing.T, databaseType string) []byte {
+	w, err := writer.New(geoip2.Metadata{
+		DatabaseType: databaseType,
+		Languages:    []string{"en"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Insert(netip.MustParsePrefix("81.2.69.0/24"), map[string]interface{}{
+		"country": map[string]interface{}{
+			"iso_code": "GB",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := &bytes.Buffer{}
+	_, err = w.WriteTo(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func newTestServer(t *testing.T, editionID string, database []byte, md5 string) *httptest.Server {
+	archive := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+	err := tarWriter.WriteHeader(&tar.Header{
+		Name:     editionID + "_20240102/" + editionID + ".mmdb",
+		Mode:     0o644,
+		Size:     int64(len(database)),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tarWriter.Write(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tarWriter.Close() != nil || gzipWriter.Close() != nil {
+		t.Fatal()
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/geoip/updates/metadata", func(w http.ResponseWriter, r *http.Request) {
+		accountID, licenseKey, ok := r.BasicAuth()
+		if !ok || accountID != "42" || licenseKey != "license" {
+			http.Error(w, `{"code":"ACCOUNT_ID_REQUIRED","error":"Account ID required"}`, http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("edition_id") != editionID {
+			http.Error(w, "", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"databases":[{"edition_id":"` + editionID + `","md5":"` + md5 + `","date":"2024-01-02"}]}`))
+	})
+	mux.HandleFunc("/geoip/databases/"+editionID+"/download", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("date") != "20240102" || r.URL.Query().Get("suffix") != "tar.gz" {
+			http.Error(w, "", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(archive.Bytes())
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestUpdate(t *testing.T) {
+	database := newTestDatabase(t, "GeoIP2-City")
+	hash := md5.Sum(database)
+	server := newTestServer(t, "GeoIP2-City", database, hex.EncodeToString(hash[:]))
+	directory := t.TempDir()
+
+	client := NewClient(42, "wrong")
+	client.Host = server.URL
+	_, err := client.Update(context.Background(), directory, "GeoIP2-City")
+	if err == nil {
+		t.Fatal()
+	}
+
+	client = NewClient(42, "license")
+	client.Host = server.URL
+	updated, err := client.Update(context.Background(), directory, "GeoIP2-City")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated {
+		t.Fatal()
+	}
+	installed, err := os.ReadFile(filepath.Join(directory, "GeoIP2-City.mmdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(installed, database) {
+		t.Fatal()
+	}
+	updated, err = client.Update(context.Background(), directory, "GeoIP2-City")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated {
+		t.Fatal()
+	}
+}
+
+func TestUpdateInvalid(t *testing.T) {
+	database := newTestDatabase(t, "GeoIP2-Country")
+	hash := md5.Sum(database)
+	directory := t.TempDir()
+
+	server := newTestServer(t, "GeoIP2-City", database, hex.EncodeToString(hash[:]))
+	client := NewClient(42, "license")
+	client.Host = server.URL
+	_, err := client.Update(context.Background(), directory, "GeoIP2-City")
+	if err == nil {
+		t.Fatal()
+	}
+
+	server = newTestServer(t, "GeoIP2-Country", database, "00000000000000000000000000000000")
+	client.Host = server.URL
+	_, err = client.Update(context.Background(), directory, "GeoIP2-Country")
+	if err == nil {
+		t.Fatal()
+	}
+
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatal(len(entries))
+	}
+}
@@ -0,0 +1,276 @@
+package geoip2
+
+import "strconv"
+
+const verifyNodeVisiting = 0xFF
+
+type verifier struct {
+	reader   *reader
+	bitCount uint
+	// heights holds the height of every verified node plus one, zero for
+	// nodes not visited yet and verifyNodeVisiting for nodes on the stack.
+	heights []byte
+	records map[uint]bool
+}
+
+// Verify checks the whole database against the MaxMind DB format: the
+// metadata, the data section separator, every node of the search tree and
+// every value of the data section.
+func (r *reader) Verify() error {
+	err := r.verifyMetadata()
+	if err != nil {
+		return err
+	}
+	searchTreeSize := uint(len(r.nodeBuffer))
+	for _, b := range r.buffer[searchTreeSize : searchTreeSize+dataSectionSeparatorSize] {
+		if b != 0 {
+			return newInvalidDatabaseError(searchTreeSize, "data section separator is not zeroed")
+		}
+	}
+	v := &verifier{
+		reader:   r,
+		bitCount: 128,
+		heights:  make([]byte, r.metadata.NodeCount),
+		records:  map[uint]bool{},
+	}
+	if r.metadata.IPVersion == 4 {
+		v.bitCount = 32
+	}
+	_, err = v.verifyNode(0, 0)
+	if err != nil {
+		return err
+	}
+	for offset := range v.records {
+		err = v.verifyRecord(offset, 0)
+		if err != nil {
+			return err
+		}
+	}
+	for offset := uint(0); offset < uint(len(r.decoderBuffer)); {
+		offset, err = v.verifyValue(offset, 0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *reader) verifyMetadata() error {
+	metadata := r.metadata
+	if metadata.BinaryFormatMajorVersion != 2 {
+		return newInvalidDatabaseError(0, "unsupported binary format major version: "+strconv.Itoa(int(metadata.BinaryFormatMajorVersion)))
+	}
+	if metadata.RecordSize != 24 && metadata.RecordSize != 28 && metadata.RecordSize != 32 {
+		return newInvalidDatabaseError(0, "invalid record size: "+strconv.Itoa(int(metadata.RecordSize)))
+	}
+	if metadata.IPVersion != 4 && metadata.IPVersion != 6 {
+		return newInvalidDatabaseError(0, "invalid IP version: "+strconv.Itoa(int(metadata.IPVersion)))
+	}
+	if metadata.NodeCount == 0 {
+		return newInvalidDatabaseError(0, "no search tree nodes")
+	}
+	if metadata.DatabaseType == "" {
+		return newInvalidDatabaseError(0, "no database type")
+	}
+	return nil
+}
+
+// verifyNode returns the height of the subtree rooted at node.
+func (v *verifier) verifyNode(node uint, depth uint) (byte, error) {
+	switch height := v.heights[node]; height {
+	case 0:
+	case verifyNodeVisiting:
+		return 0, newInvalidDatabaseError(node*v.reader.nodeOffsetMult, "cycle in search tree")
+	default:
+		if depth+uint(height)-1 > v.bitCount {
+			return 0, newInvalidDatabaseError(node*v.reader.nodeOffsetMult, "search tree is too deep")
+		}
+		return height - 1, nil
+	}
+	if depth >= v.bitCount {
+		return 0, newInvalidDatabaseError(node*v.reader.nodeOffsetMult, "search tree is too deep")
+	}
+	v.heights[node] = verifyNodeVisiting
+	offset := node * v.reader.nodeOffsetMult
+	height := byte(0)
+	for _, record := range [2]uint{v.reader.readLeft(offset), v.reader.readRight(offset)} {
+		recordHeight, err := v.verifySearchTreeRecord(record, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		if recordHeight+1 > height {
+			height = recordHeight + 1
+		}
+	}
+	v.heights[node] = height + 1
+	return height, nil
+}
+
+func (v *verifier) verifySearchTreeRecord(record uint, depth uint) (byte, error) {
+	nodeCount := uint(v.reader.metadata.NodeCount)
+	if record < nodeCount {
+		return v.verifyNode(record, depth)
+	}
+	if record == nodeCount {
+		return 0, nil
+	}
+	offset := record - nodeCount - dataSectionSeparatorSize
+	if record < nodeCount+dataSectionSeparatorSize || offset >= uint(len(v.reader.decoderBuffer)) {
+		return 0, newInvalidDatabaseError(offset, "the search tree points outside the data section: "+strconv.Itoa(int(record)))
+	}
+	v.records[offset] = false
+	return 0, nil
+}
+
+// verifyRecord verifies the value at offset once, no matter how often it is
+// referenced by the search tree or by pointers.
+func (v *verifier) verifyRecord(offset uint, depth uint) error {
+	verified, ok := v.records[offset]
+	if ok && verified {
+		return nil
+	}
+	_, err := v.verifyValue(offset, depth)
+	if err != nil {
+		return err
+	}
+	v.records[offset] = true
+	return nil
+}
+
+func (v *verifier) verifyValue(offset uint, depth uint) (uint, error) {
+	if depth > maxDataDepth {
+		return 0, newInvalidDatabaseError(offset, "maximum data structure depth exceeded")
+	}
+	buffer := v.reader.decoderBuffer
+	dataType, size, dataOffset, err := v.verifyControl(offset)
+	if err != nil {
+		return 0, err
+	}
+	switch dataType {
+	case dataTypePointer:
+		pointer, newOffset, err := v.verifyPointer(size, dataOffset)
+		if err != nil {
+			return 0, err
+		}
+		err = v.verifyRecord(pointer, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		return newOffset, nil
+	case dataTypeMap:
+		offset = dataOffset
+		for i := uint(0); i < size; i++ {
+			offset, err = v.verifyMapKey(offset)
+			if err != nil {
+				return 0, err
+			}
+			offset, err = v.verifyValue(offset, depth+1)
+			if err != nil {
+				return 0, err
+			}
+		}
+		return offset, nil
+	case dataTypeSlice:
+		offset = dataOffset
+		for i := uint(0); i < size; i++ {
+			offset, err = v.verifyValue(offset, depth+1)
+			if err != nil {
+				return 0, err
+			}
+		}
+		return offset, nil
+	case dataTypeBool:
+		if size > 1 {
+			return 0, newInvalidDatabaseError(offset, "invalid bool size: "+strconv.Itoa(int(size)))
+		}
+		return dataOffset, nil
+	case dataTypeString, dataTypeBytes:
+	case dataTypeFloat64:
+		if size != 8 {
+			return 0, newInvalidDatabaseError(offset, "invalid float64 size: "+strconv.Itoa(int(size)))
+		}
+	case dataTypeFloat32:
+		if size != 4 {
+			return 0, newInvalidDatabaseError(offset, "invalid float32 size: "+strconv.Itoa(int(size)))
+		}
+	case dataTypeUint16:
+		if size > 2 {
+			return 0, newInvalidDatabaseError(offset, "invalid uint16 size: "+strconv.Itoa(int(size)))
+		}
+	case dataTypeUint32:
+		if size > 4 {
+			return 0, newInvalidDatabaseError(offset, "invalid uint32 size: "+strconv.Itoa(int(size)))
+		}
+	case dataTypeInt32:
+		if size > 4 {
+			return 0, newInvalidDatabaseError(offset, "invalid int32 size: "+strconv.Itoa(int(size)))
+		}
+	case dataTypeUint64:
+		if size > 8 {
+			return 0, newInvalidDatabaseError(offset, "invalid uint64 size: "+strconv.Itoa(int(size)))
+		}
+	case dataTypeUint128:
+		if size > 16 {
+			return 0, newInvalidDatabaseError(offset, "invalid uint128 size: "+strconv.Itoa(int(size)))
+		}
+	default:
+		return 0, newInvalidDatabaseError(offset, "invalid data type: "+strconv.Itoa(int(dataType)))
+	}
+	newOffset := dataOffset + size
+	if newOffset > uint(len(buffer)) {
+		return 0, newInvalidDatabaseError(offset, "data section is truncated")
+	}
+	return newOffset, nil
+}
+
+func (v *verifier) verifyControl(offset uint) (byte, uint, uint, error) {
+	buffer := v.reader.decoderBuffer
+	if offset >= uint(len(buffer)) || (buffer[offset]>>5 == dataTypeExtended && offset+1 >= uint(len(buffer))) {
+		return 0, 0, 0, newInvalidDatabaseError(offset, "data section is truncated")
+	}
+	return readControl(buffer, offset)
+}
+
+func (v *verifier) verifyPointer(size uint, offset uint) (uint, uint, error) {
+	buffer := v.reader.decoderBuffer
+	pointer, newOffset, err := readPointer(buffer, size, offset)
+	if err != nil {
+		return 0, 0, err
+	}
+	if pointer >= uint(len(buffer)) {
+		return 0, 0, newInvalidDatabaseError(offset, "invalid pointer: "+strconv.Itoa(int(pointer)))
+	}
+	if buffer[pointer]>>5 == dataTypePointer {
+		return 0, 0, newInvalidDatabaseError(pointer, "invalid pointer to pointer")
+	}
+	return pointer, newOffset, nil
+}
+
+func (v *verifier) verifyMapKey(offset uint) (uint, error) {
+	dataType, size, dataOffset, err := v.verifyControl(offset)
+	if err != nil {
+		return 0, err
+	}
+	newOffset := uint(0)
+	if dataType == dataTypePointer {
+		pointer, pointerOffset, err := v.verifyPointer(size, dataOffset)
+		if err != nil {
+			return 0, err
+		}
+		newOffset = pointerOffset
+		dataType, size, dataOffset, err = v.verifyControl(pointer)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if dataType != dataTypeString {
+		return 0, newUnexpectedTypeError("map key", dataTypeString, dataType)
+	}
+	if dataOffset+size > uint(len(v.reader.decoderBuffer)) {
+		return 0, newInvalidDatabaseError(offset, "data section is truncated")
+	}
+	if newOffset == 0 {
+		newOffset = dataOffset + size
+	}
+	return newOffset, nil
+}
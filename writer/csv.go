@@ -0,0 +1,452 @@
+package writer
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CSVImporter inserts the networks of a database in the MaxMind CSV format,
+// i.e. GeoIP2 and GeoLite2 City and Country CSV databases, into a Writer. The
+// records have the layout of the MaxMind binary databases. The CSV format has
+// no GeoNames IDs of subdivisions and no type of represented countries, so
+// these are missing from the records.
+type CSVImporter struct {
+	writer    *Writer
+	locations map[uint32]*csvLocation
+	// Candidate countries and continents by code, see index.
+	countries  map[string][]uint32
+	continents map[string][]uint32
+	indexed    bool
+	// Registered and represented countries by ISO code, see countryID.
+	registeredCountries map[string]uint32
+	records             map[string]*value
+}
+
+type csvLocation struct {
+	continentCode     string
+	continentNames    map[string]string
+	countryISOCode    string
+	countryNames      map[string]string
+	subdivisions      [2]csvSubdivision
+	cityNames         map[string]string
+	metroCode         uint16
+	timeZone          string
+	isInEuropeanUnion bool
+}
+
+type csvSubdivision struct {
+	isoCode string
+	names   map[string]string
+}
+
+// NewCSVImporter returns a CSVImporter inserting into writer.
+func NewCSVImporter(writer *Writer) *CSVImporter {
+	return &CSVImporter{
+		writer:              writer,
+		locations:           map[uint32]*csvLocation{},
+		registeredCountries: map[string]uint32{},
+		records:             map[string]*value{},
+	}
+}
+
+// ReadLocations reads a Locations file, e.g. GeoLite2-City-Locations-en.csv.
+// Every language is read from its own file and added to the languages of the
+// metadata. Locations have to be read before the networks referring to them.
+func (i *CSVImporter) ReadLocations(reader io.Reader) error {
+	rows, err := newCSVRows(reader, "geoname_id", "locale_code")
+	if err != nil {
+		return err
+	}
+	for rows.next() {
+		geoNameID, err := rows.uint32("geoname_id")
+		if err != nil {
+			return err
+		}
+		if geoNameID == 0 {
+			return rows.lineError("missing geoname_id")
+		}
+		language := rows.get("locale_code")
+		if language == "" {
+			return rows.lineError("missing locale_code")
+		}
+		i.addLanguage(language)
+		location := i.locations[geoNameID]
+		if location == nil {
+			location = &csvLocation{}
+			i.locations[geoNameID] = location
+		}
+		location.continentCode = rows.get("continent_code")
+		addName(&location.continentNames, language, rows.get("continent_name"))
+		location.countryISOCode = rows.get("country_iso_code")
+		addName(&location.countryNames, language, rows.get("country_name"))
+		for j, prefix := range []string{"subdivision_1_", "subdivision_2_"} {
+			location.subdivisions[j].isoCode = rows.get(prefix + "iso_code")
+			addName(&location.subdivisions[j].names, language, rows.get(prefix+"name"))
+		}
+		addName(&location.cityNames, language, rows.get("city_name"))
+		location.metroCode, err = rows.uint16("metro_code")
+		if err != nil {
+			return err
+		}
+		location.timeZone = rows.get("time_zone")
+		location.isInEuropeanUnion = rows.get("is_in_european_union") == "1"
+	}
+	i.indexed = false
+	i.records = map[string]*value{}
+	return rows.err()
+}
+
+func (i *CSVImporter) addLanguage(language string) {
+	metadata := &i.writer.metadata
+	for _, current := range metadata.Languages {
+		if current == language {
+			return
+		}
+	}
+	metadata.Languages = append(metadata.Languages, language)
+	sort.Strings(metadata.Languages)
+}
+
+func addName(names *map[string]string, language string, name string) {
+	if name == "" {
+		return
+	}
+	if *names == nil {
+		*names = map[string]string{}
+	}
+	(*names)[language] = name
+}
+
+// InsertBlocks reads a Blocks file, e.g. GeoLite2-City-Blocks-IPv4.csv, and
+// inserts its networks.
+func (i *CSVImporter) InsertBlocks(reader io.Reader) error {
+	rows, err := newCSVRows(reader, "network")
+	if err != nil {
+		return err
+	}
+	i.index()
+	for rows.next() {
+		network, err := netip.ParsePrefix(rows.get("network"))
+		if err != nil {
+			return rows.lineError(err.Error())
+		}
+		ip, bitCount, err := i.writer.network(network)
+		if err != nil {
+			return rows.lineError(err.Error())
+		}
+		// Networks with the same columns share their record.
+		key := rows.key()
+		record, ok := i.records[key]
+		if !ok {
+			fields, err := i.record(rows)
+			if err != nil {
+				return err
+			}
+			record, err = i.writer.values.newValue(fields)
+			if err != nil {
+				return rows.lineError(err.Error())
+			}
+			i.records[key] = record
+		}
+		i.writer.root.insert(ip, bitCount, record)
+	}
+	return rows.err()
+}
+
+// index finds the candidate GeoNames IDs of the countries and continents,
+// which the Locations files only have as locations without a city name or
+// subdivision, and without a country respectively. A city without names is
+// a candidate as well, so the candidates of a country are sorted to resolve
+// it deterministically, see countryID.
+func (i *CSVImporter) index() {
+	if i.indexed {
+		return
+	}
+	i.countries = map[string][]uint32{}
+	i.continents = map[string][]uint32{}
+	for geoNameID, location := range i.locations {
+		if location.cityNames != nil || location.subdivisions[0].isoCode != "" {
+			continue
+		}
+		if location.countryISOCode != "" {
+			i.countries[location.countryISOCode] = append(i.countries[location.countryISOCode], geoNameID)
+		} else if location.continentCode != "" {
+			i.continents[location.continentCode] = append(i.continents[location.continentCode], geoNameID)
+		}
+	}
+	for _, candidates := range []map[string][]uint32{i.countries, i.continents} {
+		for _, ids := range candidates {
+			sort.Slice(ids, func(a, b int) bool {
+				return ids[a] < ids[b]
+			})
+		}
+	}
+	i.indexed = true
+}
+
+// countryID returns the GeoNames ID of the country with isoCode. The
+// registered and represented countries of the network, or else of the networks
+// inserted before, name the ID of their country; otherwise the first candidate
+// of the index is used.
+func (i *CSVImporter) countryID(isoCode string, countries map[string]uint32) uint32 {
+	if id, ok := countries[isoCode]; ok {
+		return id
+	}
+	if id, ok := i.registeredCountries[isoCode]; ok {
+		return id
+	}
+	if ids := i.countries[isoCode]; len(ids) != 0 {
+		return ids[0]
+	}
+	return 0
+}
+
+func (i *CSVImporter) record(rows *csvRows) (map[string]interface{}, error) {
+	record := map[string]interface{}{}
+	// The registered and represented countries by ISO code.
+	countries := map[string]uint32{}
+	for _, column := range []string{"registered_country", "represented_country"} {
+		id, err := rows.uint32(column + "_geoname_id")
+		if err != nil {
+			return nil, err
+		}
+		if id != 0 {
+			location, err := i.location(rows, id)
+			if err != nil {
+				return nil, err
+			}
+			record[column] = country(id, location)
+			if location.countryISOCode != "" {
+				countries[location.countryISOCode] = id
+				i.registeredCountries[location.countryISOCode] = id
+			}
+		}
+	}
+
+	geoNameID, err := rows.uint32("geoname_id")
+	if err != nil {
+		return nil, err
+	}
+	locationFields := map[string]interface{}{}
+	if geoNameID != 0 {
+		location, err := i.location(rows, geoNameID)
+		if err != nil {
+			return nil, err
+		}
+		// The location is a city unless it is a continent or a country.
+		isCity := location.countryISOCode != ""
+		if location.continentCode != "" {
+			continent := map[string]interface{}{
+				"code": location.continentCode,
+			}
+			if ids := i.continents[location.continentCode]; len(ids) != 0 {
+				continent["geoname_id"] = ids[0]
+			}
+			if location.continentNames != nil {
+				continent["names"] = location.continentNames
+			}
+			record["continent"] = continent
+		}
+		if location.countryISOCode != "" {
+			// A location of a country without a known ID is only a city if it
+			// has a city name, as it may be the country itself.
+			countryID := i.countryID(location.countryISOCode, countries)
+			isCity = location.cityNames != nil || countryID != 0 && geoNameID != countryID
+			record["country"] = country(countryID, location)
+		}
+		var subdivisions []interface{}
+		for _, subdivision := range location.subdivisions {
+			if subdivision.isoCode == "" {
+				break
+			}
+			fields := map[string]interface{}{
+				"iso_code": subdivision.isoCode,
+			}
+			if subdivision.names != nil {
+				fields["names"] = subdivision.names
+			}
+			subdivisions = append(subdivisions, fields)
+		}
+		if subdivisions != nil {
+			record["subdivisions"] = subdivisions
+		}
+		if isCity {
+			city := map[string]interface{}{
+				"geoname_id": geoNameID,
+			}
+			if location.cityNames != nil {
+				city["names"] = location.cityNames
+			}
+			record["city"] = city
+		}
+		if location.metroCode != 0 {
+			locationFields["metro_code"] = location.metroCode
+		}
+		if location.timeZone != "" {
+			locationFields["time_zone"] = location.timeZone
+		}
+	}
+
+	traits := map[string]interface{}{}
+	for _, column := range []string{"is_anonymous_proxy", "is_satellite_provider", "is_anycast"} {
+		if rows.get(column) == "1" {
+			traits[column] = true
+		}
+	}
+	if len(traits) != 0 {
+		record["traits"] = traits
+	}
+	if code := rows.get("postal_code"); code != "" {
+		record["postal"] = map[string]interface{}{
+			"code": code,
+		}
+	}
+	for _, column := range []string{"latitude", "longitude"} {
+		if rows.get(column) == "" {
+			continue
+		}
+		coordinate, err := strconv.ParseFloat(rows.get(column), 64)
+		if err != nil {
+			return nil, rows.lineError("invalid " + column + ": " + rows.get(column))
+		}
+		locationFields[column] = coordinate
+	}
+	accuracyRadius, err := rows.uint16("accuracy_radius")
+	if err != nil {
+		return nil, err
+	}
+	if accuracyRadius != 0 {
+		locationFields["accuracy_radius"] = accuracyRadius
+	}
+	if len(locationFields) != 0 {
+		record["location"] = locationFields
+	}
+	return record, nil
+}
+
+func (i *CSVImporter) location(rows *csvRows, geoNameID uint32) (*csvLocation, error) {
+	location := i.locations[geoNameID]
+	if location == nil {
+		return nil, rows.lineError("unknown geoname_id: " + strconv.FormatUint(uint64(geoNameID), 10))
+	}
+	return location, nil
+}
+
+func country(geoNameID uint32, location *csvLocation) map[string]interface{} {
+	country := map[string]interface{}{}
+	if geoNameID != 0 {
+		country["geoname_id"] = geoNameID
+	}
+	if location.countryISOCode != "" {
+		country["iso_code"] = location.countryISOCode
+	}
+	if location.countryNames != nil {
+		country["names"] = location.countryNames
+	}
+	if location.isInEuropeanUnion {
+		country["is_in_european_union"] = true
+	}
+	return country
+}
+
+// csvRows reads the rows of a CSV file with a header.
+type csvRows struct {
+	reader  *csv.Reader
+	columns map[string]int
+	row     []string
+	readErr error
+}
+
+func newCSVRows(reader io.Reader, required ...string) (*csvRows, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.ReuseRecord = true
+	header, err := csvReader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("missing CSV header")
+		}
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[column] = i
+	}
+	for _, column := range required {
+		if _, ok := columns[column]; !ok {
+			return nil, errors.New("missing CSV column: " + column)
+		}
+	}
+	return &csvRows{
+		reader:  csvReader,
+		columns: columns,
+	}, nil
+}
+
+func (r *csvRows) next() bool {
+	row, err := r.reader.Read()
+	if err != nil {
+		if err != io.EOF {
+			r.readErr = err
+		}
+		return false
+	}
+	r.row = row
+	return true
+}
+
+func (r *csvRows) err() error {
+	return r.readErr
+}
+
+// get returns the value of column, or an empty string if there is no such
+// column.
+func (r *csvRows) get(column string) string {
+	i, ok := r.columns[column]
+	if !ok {
+		return ""
+	}
+	return r.row[i]
+}
+
+// key returns the values of the row except its network.
+func (r *csvRows) key() string {
+	values := make([]string, 0, len(r.row)-1)
+	for i, value := range r.row {
+		if i != r.columns["network"] {
+			values = append(values, value)
+		}
+	}
+	return strings.Join(values, "\x00")
+}
+
+func (r *csvRows) uint32(column string) (uint32, error) {
+	value, err := r.uint(column, 32)
+	return uint32(value), err
+}
+
+func (r *csvRows) uint16(column string) (uint16, error) {
+	value, err := r.uint(column, 16)
+	return uint16(value), err
+}
+
+func (r *csvRows) uint(column string, bitSize int) (uint64, error) {
+	value := r.get(column)
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.ParseUint(value, 10, bitSize)
+	if err != nil {
+		return 0, r.lineError("invalid " + column + ": " + value)
+	}
+	return number, nil
+}
+
+func (r *csvRows) lineError(message string) error {
+	line, _ := r.reader.FieldPos(0)
+	return errors.New("line " + strconv.Itoa(line) + ": " + message)
+}
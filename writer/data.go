@@ -0,0 +1,363 @@
+package writer
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+const (
+	dataTypePointer = 1
+	dataTypeString  = 2
+	dataTypeFloat64 = 3
+	dataTypeBytes   = 4
+	dataTypeUint16  = 5
+	dataTypeUint32  = 6
+	dataTypeMap     = 7
+	dataTypeInt32   = 8
+	dataTypeUint64  = 9
+	dataTypeUint128 = 10
+	dataTypeSlice   = 11
+	dataTypeBool    = 14
+	dataTypeFloat32 = 15
+
+	maxDataSize = 65821 + 1<<24 - 1
+)
+
+var bigIntType = reflect.TypeOf(big.Int{})
+
+// value is an encoded MaxMind DB value. Maps and slices keep their children so
+// that the data section can replace repeated children with pointers.
+type value struct {
+	// encoded is the encoding of the value without pointers. It identifies
+	// equal values.
+	encoded  string
+	children []*value
+}
+
+func (v *value) isMap() bool {
+	return v.encoded[0]>>5 == dataTypeMap
+}
+
+// payload returns the encoding of a scalar value without its control bytes.
+func (v *value) payload() string {
+	controlSize := 1
+	if v.encoded[0]>>5 == 0 {
+		controlSize++
+	}
+	switch v.encoded[0] & 0x1F {
+	case 29:
+		controlSize++
+	case 30:
+		controlSize += 2
+	case 31:
+		controlSize += 3
+	}
+	return v.encoded[controlSize:]
+}
+
+// values interns the encoded values of a writer.
+type values map[string]*value
+
+func (v values) intern(encoded []byte, children []*value) *value {
+	result, ok := v[string(encoded)]
+	if ok {
+		return result
+	}
+	result = &value{
+		encoded:  string(encoded),
+		children: children,
+	}
+	v[result.encoded] = result
+	return result
+}
+
+func (v values) newValue(input interface{}) (*value, error) {
+	return v.newReflectValue(reflect.ValueOf(input))
+}
+
+func (v values) newReflectValue(input reflect.Value) (*value, error) {
+	switch input.Kind() {
+	case reflect.Invalid:
+		return nil, errors.New("nil values are not supported")
+	case reflect.Ptr, reflect.Interface:
+		if input.IsNil() {
+			return nil, errors.New("nil values are not supported")
+		}
+		if input.Kind() == reflect.Ptr && input.Type().Elem() == bigIntType {
+			return v.newUint128(input.Interface().(*big.Int))
+		}
+		return v.newReflectValue(input.Elem())
+	case reflect.String:
+		return v.newScalar(dataTypeString, []byte(input.String()))
+	case reflect.Bool:
+		size := uint(0)
+		if input.Bool() {
+			size = 1
+		}
+		return v.intern(appendControl(nil, dataTypeBool, size), nil), nil
+	case reflect.Float64:
+		return v.newScalar(dataTypeFloat64, uint64Bytes(math.Float64bits(input.Float())))
+	case reflect.Float32:
+		return v.newScalar(dataTypeFloat32, uint64Bytes(uint64(math.Float32bits(float32(input.Float()))))[4:])
+	case reflect.Uint8, reflect.Uint16:
+		return v.newScalar(dataTypeUint16, trimLeadingZeros(uint64Bytes(uint64(uint16(input.Uint())))))
+	case reflect.Uint32:
+		return v.newScalar(dataTypeUint32, trimLeadingZeros(uint64Bytes(uint64(uint32(input.Uint())))))
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return v.newScalar(dataTypeUint64, trimLeadingZeros(uint64Bytes(input.Uint())))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number := input.Int()
+		if number < math.MinInt32 || number > math.MaxInt32 {
+			return nil, errors.New("value " + strconv.FormatInt(number, 10) + " overflows int32")
+		}
+		payload := uint64Bytes(uint64(uint32(number)))[4:]
+		if number >= 0 {
+			payload = trimLeadingZeros(payload)
+		}
+		return v.newScalar(dataTypeInt32, payload)
+	case reflect.Slice:
+		if input.IsNil() {
+			return nil, errors.New("nil values are not supported")
+		}
+		if input.Type().Elem().Kind() == reflect.Uint8 {
+			return v.newScalar(dataTypeBytes, input.Bytes())
+		}
+		fallthrough
+	case reflect.Array:
+		children := make([]*value, input.Len())
+		for i := range children {
+			child, err := v.newReflectValue(input.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			children[i] = child
+		}
+		return v.newContainer(dataTypeSlice, uint(len(children)), children), nil
+	case reflect.Map:
+		if input.Type().Key().Kind() != reflect.String {
+			return nil, errors.New("map keys must be strings, got " + input.Type().Key().String())
+		}
+		if input.IsNil() {
+			return nil, errors.New("nil values are not supported")
+		}
+		keys := input.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		children := make([]*value, 0, 2*len(keys))
+		for _, key := range keys {
+			keyValue, err := v.newScalar(dataTypeString, []byte(key.String()))
+			if err != nil {
+				return nil, err
+			}
+			child, err := v.newReflectValue(input.MapIndex(key))
+			if err != nil {
+				return nil, errors.New(key.String() + ": " + err.Error())
+			}
+			children = append(children, keyValue, child)
+		}
+		return v.newContainer(dataTypeMap, uint(len(keys)), children), nil
+	case reflect.Struct:
+		if input.Type() == bigIntType {
+			number := input.Interface().(big.Int)
+			return v.newUint128(&number)
+		}
+		return v.newStruct(input)
+	default:
+		return nil, errors.New("unsupported type: " + input.Type().String())
+	}
+}
+
+// newStruct encodes a struct as a map. Keys are taken from the maxminddb tags
+// like the geoip2 decoder does, and zero fields are omitted.
+func (v values) newStruct(input reflect.Value) (*value, error) {
+	var fields []string
+	byName := map[string]reflect.Value{}
+	var collect func(input reflect.Value)
+	collect = func(input reflect.Value) {
+		structType := input.Type()
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			tag := field.Tag.Get("maxminddb")
+			if tag == "-" {
+				continue
+			}
+			if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+				collect(input.Field(i))
+				continue
+			}
+			if field.PkgPath != "" || input.Field(i).IsZero() {
+				continue
+			}
+			name := tag
+			if name == "" {
+				name = field.Name
+			}
+			if _, ok := byName[name]; ok {
+				continue
+			}
+			fields = append(fields, name)
+			byName[name] = input.Field(i)
+		}
+	}
+	collect(input)
+	sort.Strings(fields)
+	children := make([]*value, 0, 2*len(fields))
+	for _, name := range fields {
+		keyValue, err := v.newScalar(dataTypeString, []byte(name))
+		if err != nil {
+			return nil, err
+		}
+		child, err := v.newReflectValue(byName[name])
+		if err != nil {
+			return nil, errors.New(name + ": " + err.Error())
+		}
+		children = append(children, keyValue, child)
+	}
+	return v.newContainer(dataTypeMap, uint(len(fields)), children), nil
+}
+
+func (v values) newUint128(number *big.Int) (*value, error) {
+	if number.Sign() < 0 || number.BitLen() > 128 {
+		return nil, errors.New("value " + number.String() + " overflows uint128")
+	}
+	return v.newScalar(dataTypeUint128, number.Bytes())
+}
+
+func (v values) newScalar(dataType byte, payload []byte) (*value, error) {
+	if len(payload) > maxDataSize {
+		return nil, errors.New("value of " + strconv.Itoa(len(payload)) + " bytes is too large")
+	}
+	encoded := appendControl(make([]byte, 0, 5+len(payload)), dataType, uint(len(payload)))
+	return v.intern(append(encoded, payload...), nil), nil
+}
+
+func (v values) newContainer(dataType byte, size uint, children []*value) *value {
+	encoded := appendControl(nil, dataType, size)
+	for _, child := range children {
+		encoded = append(encoded, child.encoded...)
+	}
+	return v.intern(encoded, children)
+}
+
+func uint64Bytes(number uint64) []byte {
+	result := make([]byte, 8)
+	binary.BigEndian.PutUint64(result, number)
+	return result
+}
+
+func trimLeadingZeros(payload []byte) []byte {
+	for len(payload) != 0 && payload[0] == 0 {
+		payload = payload[1:]
+	}
+	return payload
+}
+
+func appendControl(buffer []byte, dataType byte, size uint) []byte {
+	control := dataType << 5
+	if dataType > 7 {
+		control = 0
+	}
+	var sizeBytes []byte
+	switch {
+	case size < 29:
+		control |= byte(size)
+	case size < 285:
+		control |= 29
+		sizeBytes = []byte{byte(size - 29)}
+	case size < 65821:
+		control |= 30
+		size -= 285
+		sizeBytes = []byte{byte(size >> 8), byte(size)}
+	default:
+		control |= 31
+		size -= 65821
+		sizeBytes = []byte{byte(size >> 16), byte(size >> 8), byte(size)}
+	}
+	buffer = append(buffer, control)
+	if dataType > 7 {
+		buffer = append(buffer, dataType-7)
+	}
+	return append(buffer, sizeBytes...)
+}
+
+func appendPointer(buffer []byte, pointer uint) []byte {
+	switch {
+	case pointer < 2048:
+		return append(buffer, dataTypePointer<<5|byte(pointer>>8)&0x7, byte(pointer))
+	case pointer < 526336:
+		pointer -= 2048
+		return append(buffer, dataTypePointer<<5|0x08|byte(pointer>>16)&0x7, byte(pointer>>8), byte(pointer))
+	case pointer < 134744064:
+		pointer -= 526336
+		return append(buffer, dataTypePointer<<5|0x10|byte(pointer>>24)&0x7, byte(pointer>>16), byte(pointer>>8), byte(pointer))
+	default:
+		return append(buffer, dataTypePointer<<5|0x18, byte(pointer>>24), byte(pointer>>16), byte(pointer>>8), byte(pointer))
+	}
+}
+
+func pointerSize(pointer uint) int {
+	switch {
+	case pointer < 2048:
+		return 2
+	case pointer < 526336:
+		return 3
+	case pointer < 134744064:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// dataSection writes values once and refers to repeated values with pointers
+// where a pointer is shorter than the value.
+type dataSection struct {
+	buffer  []byte
+	offsets map[*value]uint
+}
+
+func newDataSection() *dataSection {
+	return &dataSection{
+		offsets: map[*value]uint{},
+	}
+}
+
+// record returns the offset of v, writing it if it is not in the data section
+// yet.
+func (d *dataSection) record(v *value) uint {
+	offset, ok := d.offsets[v]
+	if ok {
+		return offset
+	}
+	offset = uint(len(d.buffer))
+	d.write(v)
+	return offset
+}
+
+func (d *dataSection) write(v *value) {
+	offset, ok := d.offsets[v]
+	if ok && pointerSize(offset) < len(v.encoded) {
+		d.buffer = appendPointer(d.buffer, offset)
+		return
+	}
+	if !ok {
+		d.offsets[v] = uint(len(d.buffer))
+	}
+	if v.children == nil {
+		d.buffer = append(d.buffer, v.encoded...)
+		return
+	}
+	controlSize := len(v.encoded)
+	for _, child := range v.children {
+		controlSize -= len(child.encoded)
+	}
+	d.buffer = append(d.buffer, v.encoded[:controlSize]...)
+	for _, child := range v.children {
+		d.write(child)
+	}
+}
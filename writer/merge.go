@@ -0,0 +1,122 @@
+package writer
+
+import (
+	"net/netip"
+	"sort"
+
+	"github.com/IncSW/geoip2"
+)
+
+// NewFromReader returns a Writer holding the networks of reader, described by
+// its metadata. An IPv6 database with networks of its own within the IPv4
+// aliases, e.g. one written with WithoutIPv4Aliases, needs WithoutIPv4Aliases
+// too, see Insert.
+func NewFromReader(reader *geoip2.Reader, opts ...Option) (*Writer, error) {
+	// The strings of the metadata may point into a memory-mapped database.
+	metadata := *reader.Metadata()
+	metadata.DatabaseType = string([]byte(metadata.DatabaseType))
+	metadata.Languages = make([]string, len(metadata.Languages))
+	for i, language := range reader.Metadata().Languages {
+		metadata.Languages[i] = string([]byte(language))
+	}
+	metadata.Description = make(map[string]string, len(metadata.Description))
+	for language, description := range reader.Metadata().Description {
+		metadata.Description[string([]byte(language))] = string([]byte(description))
+	}
+	metadata.BuildEpoch = 0
+	writer, err := New(metadata, opts...)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.InsertNetworks(reader)
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// InsertNetworks inserts every network of reader, see Insert.
+func (w *Writer) InsertNetworks(reader *geoip2.Reader) error {
+	return w.insertNetworks(reader, w.root.insert)
+}
+
+// MergeNetworks merges every network of reader, see Merge.
+func (w *Writer) MergeNetworks(reader *geoip2.Reader) error {
+	return w.insertNetworks(reader, w.merge)
+}
+
+func (w *Writer) insertNetworks(reader *geoip2.Reader, insert func(ip []byte, bitCount uint, encoded *value)) error {
+	// Networks sharing a record share its value.
+	records := map[uint]*value{}
+	networks := reader.Networks()
+	for networks.Next() {
+		ip, bitCount, err := w.network(networks.Network())
+		if err != nil {
+			return err
+		}
+		encoded, ok := records[networks.Offset()]
+		if !ok {
+			record, err := networks.Record()
+			if err != nil {
+				return err
+			}
+			encoded, err = w.values.newValue(record)
+			if err != nil {
+				return err
+			}
+			records[networks.Offset()] = encoded
+		}
+		insert(ip, bitCount, encoded)
+	}
+	return networks.Err()
+}
+
+// Merge deep-merges value into the values of the network and of the networks
+// within it: maps are merged key by key and the other values of value replace
+// the existing ones. Networks without a value are set to value.
+func (w *Writer) Merge(network netip.Prefix, value interface{}) error {
+	ip, bitCount, err := w.network(network)
+	if err != nil {
+		return err
+	}
+	encoded, err := w.values.newValue(value)
+	if err != nil {
+		return err
+	}
+	w.merge(ip, bitCount, encoded)
+	return nil
+}
+
+func (w *Writer) merge(ip []byte, bitCount uint, encoded *value) {
+	w.root.update(ip, bitCount, func(existing *value) *value {
+		return w.values.merge(existing, encoded)
+	})
+}
+
+func (v values) merge(base *value, overlay *value) *value {
+	if base == nil || !base.isMap() || !overlay.isMap() {
+		return overlay
+	}
+	entries := map[*value]*value{}
+	var keys []*value
+	for i := 0; i < len(base.children); i += 2 {
+		keys = append(keys, base.children[i])
+		entries[base.children[i]] = base.children[i+1]
+	}
+	for i := 0; i < len(overlay.children); i += 2 {
+		key := overlay.children[i]
+		existing, ok := entries[key]
+		if !ok {
+			keys = append(keys, key)
+		}
+		entries[key] = v.merge(existing, overlay.children[i+1])
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].payload() < keys[j].payload()
+	})
+	children := make([]*value, 0, 2*len(keys))
+	for _, key := range keys {
+		children = append(children, key, entries[key])
+	}
+	return v.newContainer(dataTypeMap, uint(len(keys)), children)
+}
@@ -0,0 +1,132 @@
+package writer
+
+// record is either a node, a value or empty.
+type record struct {
+	node  *node
+	value *value
+}
+
+type node struct {
+	records [2]record
+	number  uint
+}
+
+// insert sets the records of the network of bitCount bits of ip to value.
+// Networks within it are replaced.
+func (n *node) insert(ip []byte, bitCount uint, value *value) {
+	if bitCount == 0 {
+		n.records = [2]record{{value: value}, {value: value}}
+		return
+	}
+	current := n
+	for i := uint(0); ; i++ {
+		bit := bitAt(ip, i)
+		if i == bitCount-1 {
+			current.records[bit] = record{value: value}
+			return
+		}
+		current = current.child(bit)
+	}
+}
+
+// update replaces the values of the network of bitCount bits of ip and of the
+// networks within it by update(value), where value is nil for networks without
+// a value.
+func (n *node) update(ip []byte, bitCount uint, update func(*value) *value) {
+	seen := map[*node]bool{}
+	if bitCount == 0 {
+		n.records[0] = n.records[0].update(update, seen)
+		n.records[1] = n.records[1].update(update, seen)
+		return
+	}
+	parent := n.nodeAt(ip, bitCount-1)
+	bit := bitAt(ip, bitCount-1)
+	parent.records[bit] = parent.records[bit].update(update, seen)
+}
+
+// update updates the values of the record. Nodes reachable through several
+// records are updated once.
+func (r record) update(update func(*value) *value, seen map[*node]bool) record {
+	if r.node == nil {
+		return record{value: update(r.value)}
+	}
+	if !seen[r.node] {
+		seen[r.node] = true
+		r.node.records[0] = r.node.records[0].update(update, seen)
+		r.node.records[1] = r.node.records[1].update(update, seen)
+	}
+	return r
+}
+
+// child returns the node of the record, splitting a value into a node whose
+// records both hold it.
+func (n *node) child(bit byte) *node {
+	current := n.records[bit]
+	if current.node != nil {
+		return current.node
+	}
+	child := &node{
+		records: [2]record{current, current},
+	}
+	n.records[bit] = record{node: child}
+	return child
+}
+
+// nodeAt returns the node of the network of bitCount bits of ip, creating it
+// if needed.
+func (n *node) nodeAt(ip []byte, bitCount uint) *node {
+	current := n
+	for i := uint(0); i < bitCount; i++ {
+		current = current.child(bitAt(ip, i))
+	}
+	return current
+}
+
+// copyPath returns a copy of n in which the nodes on the path to the network
+// of bitCount bits of ip are copied, so that they can be changed without
+// changing n, and the copy of the node of the network.
+func (n *node) copyPath(ip []byte, bitCount uint) (*node, *node) {
+	root := &node{
+		records: n.records,
+	}
+	current := root
+	for i := uint(0); i < bitCount; i++ {
+		bit := bitAt(ip, i)
+		child := &node{}
+		if existing := current.records[bit]; existing.node != nil {
+			child.records = existing.node.records
+		} else {
+			child.records = [2]record{existing, existing}
+		}
+		current.records[bit] = record{node: child}
+		current = child
+	}
+	return root, current
+}
+
+func bitAt(ip []byte, i uint) byte {
+	return (ip[i>>3] >> (7 - i&7)) & 1
+}
+
+// numberNodes numbers the nodes depth-first and returns them in order. Nodes
+// reachable through several records, like the IPv4 subtree, are numbered once.
+func (n *node) numberNodes() []*node {
+	var nodes []*node
+	seen := map[*node]bool{}
+	var visit func(current *node)
+	visit = func(current *node) {
+		if seen[current] {
+			return
+		}
+		seen[current] = true
+		current.number = uint(len(nodes))
+		nodes = append(nodes, current)
+		for _, record := range current.records {
+			if record.node != nil {
+				visit(record.node)
+			}
+		}
+	}
+	visit(n)
+	return nodes
+}
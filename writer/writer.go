@@ -0,0 +1,235 @@
+// Package writer builds MaxMind DB files that the geoip2 readers can open.
+package writer
+
+import (
+	"errors"
+	"io"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/IncSW/geoip2"
+)
+
+const dataSectionSeparatorSize = 16
+
+var (
+	metadataStartMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+	ipv4Aliases         = []netip.Prefix{
+		netip.MustParsePrefix("::ffff:0:0/96"),
+		netip.MustParsePrefix("2002::/16"),
+	}
+)
+
+type Option func(*options)
+
+type options struct {
+	withoutIPv4Aliases bool
+}
+
+// WithoutIPv4Aliases disables the aliases of the IPv4 networks of an IPv6
+// database at ::ffff:0:0/96 (IPv4-mapped) and 2002::/16 (6to4).
+func WithoutIPv4Aliases() Option {
+	return func(options *options) {
+		options.withoutIPv4Aliases = true
+	}
+}
+
+type Writer struct {
+	metadata geoip2.Metadata
+	options  *options
+	root     *node
+	values   values
+}
+
+// New returns a Writer of a database described by metadata. DatabaseType is
+// required, IPVersion defaults to 6 and RecordSize to 28. NodeCount and the
+// binary format version are set by the Writer, and BuildEpoch defaults to the
+// time the database is written.
+func New(metadata geoip2.Metadata, opts ...Option) (*Writer, error) {
+	if metadata.DatabaseType == "" {
+		return nil, errors.New("database type is required")
+	}
+	if metadata.IPVersion == 0 {
+		metadata.IPVersion = 6
+	}
+	if metadata.IPVersion != 4 && metadata.IPVersion != 6 {
+		return nil, errors.New("invalid IP version: " + strconv.Itoa(int(metadata.IPVersion)))
+	}
+	if metadata.RecordSize == 0 {
+		metadata.RecordSize = 28
+	}
+	if metadata.RecordSize != 24 && metadata.RecordSize != 28 && metadata.RecordSize != 32 {
+		return nil, errors.New("invalid record size: " + strconv.Itoa(int(metadata.RecordSize)))
+	}
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return &Writer{
+		metadata: metadata,
+		options:  options,
+		root:     &node{},
+		values:   values{},
+	}, nil
+}
+
+// Insert sets the value of the network, replacing the values of the networks
+// within it. Values may be strings, booleans, numbers, []byte, *big.Int and
+// maps, slices and structs of them. Go integers are stored as int32 unless
+// unsigned: uint8 and uint16 as uint16, uint32 as uint32, uint and uint64 as
+// uint64. The typed readers expect the types of the MaxMind databases, e.g.
+// uint32 for geoname_id and uint16 for accuracy_radius, so untyped constants,
+// which are ints, have to be converted. Structs are stored as maps keyed by
+// their maxminddb tags, without their zero fields. IPv4 networks of an IPv6
+// database are inserted at ::/96; networks within its aliases, see
+// WithoutIPv4Aliases, are rejected.
+func (w *Writer) Insert(network netip.Prefix, value interface{}) error {
+	ip, bitCount, err := w.network(network)
+	if err != nil {
+		return err
+	}
+	encoded, err := w.values.newValue(value)
+	if err != nil {
+		return err
+	}
+	w.root.insert(ip, bitCount, encoded)
+	return nil
+}
+
+func (w *Writer) network(network netip.Prefix) ([]byte, uint, error) {
+	if !network.IsValid() {
+		return nil, 0, errors.New("invalid network: " + network.String())
+	}
+	network = network.Masked()
+	addr := network.Addr()
+	bitCount := uint(network.Bits())
+	if addr.Is4() {
+		ip := addr.As4()
+		if w.metadata.IPVersion == 4 {
+			return ip[:], bitCount, nil
+		}
+		result := make([]byte, 16)
+		copy(result[12:], ip[:])
+		return result, bitCount + 96, nil
+	}
+	if w.metadata.IPVersion == 4 {
+		return nil, 0, errors.New("cannot insert an IPv6 network into an IPv4 database: " + network.String())
+	}
+	if !w.options.withoutIPv4Aliases {
+		for _, alias := range ipv4Aliases {
+			if alias.Bits() <= network.Bits() && alias.Contains(addr) {
+				return nil, 0, errors.New("cannot insert " + network.String() + " into the IPv4 alias " + alias.String() + " without WithoutIPv4Aliases")
+			}
+		}
+	}
+	ip := addr.As16()
+	return ip[:], bitCount, nil
+}
+
+// WriteTo writes the database to writer.
+func (w *Writer) WriteTo(writer io.Writer) (int64, error) {
+	// The aliases are added to a copy of the tree, which stays open for inserts.
+	root := w.root
+	if w.metadata.IPVersion == 6 && !w.options.withoutIPv4Aliases {
+		var ipv4 *node
+		root, ipv4 = root.copyPath(make([]byte, 16), 96)
+		for _, alias := range ipv4Aliases {
+			ip := alias.Addr().As16()
+			bitCount := uint(alias.Bits())
+			var parent *node
+			root, parent = root.copyPath(ip[:], bitCount-1)
+			parent.records[bitAt(ip[:], bitCount-1)] = record{node: ipv4}
+		}
+	}
+	nodes := root.numberNodes()
+	data := newDataSection()
+	for _, node := range nodes {
+		for _, record := range node.records {
+			if record.value != nil {
+				data.record(record.value)
+			}
+		}
+	}
+
+	nodeCount := uint(len(nodes))
+	maxRecord := uint(1)<<w.metadata.RecordSize - 1
+	if nodeCount+dataSectionSeparatorSize+uint(len(data.buffer)) > maxRecord {
+		return 0, errors.New("the database is too large for record size " + strconv.Itoa(int(w.metadata.RecordSize)))
+	}
+	nodeSize := uint(w.metadata.RecordSize) / 4
+	searchTree := make([]byte, nodeCount*nodeSize)
+	for i, node := range nodes {
+		var records [2]uint
+		for j, record := range node.records {
+			switch {
+			case record.node != nil:
+				records[j] = record.node.number
+			case record.value != nil:
+				records[j] = nodeCount + dataSectionSeparatorSize + data.record(record.value)
+			default:
+				records[j] = nodeCount
+			}
+		}
+		putNode(searchTree[uint(i)*nodeSize:], w.metadata.RecordSize, records[0], records[1])
+	}
+
+	metadata, err := w.encodeMetadata(nodeCount)
+	if err != nil {
+		return 0, err
+	}
+	written := int64(0)
+	for _, section := range [][]byte{searchTree, make([]byte, dataSectionSeparatorSize), data.buffer, metadataStartMarker, metadata} {
+		n, err := writer.Write(section)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func (w *Writer) encodeMetadata(nodeCount uint) ([]byte, error) {
+	buildEpoch := w.metadata.BuildEpoch
+	if buildEpoch == 0 {
+		buildEpoch = uint64(time.Now().Unix())
+	}
+	description := w.metadata.Description
+	if description == nil {
+		description = map[string]string{}
+	}
+	languages := w.metadata.Languages
+	if languages == nil {
+		languages = []string{}
+	}
+	metadata, err := values{}.newValue(map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 buildEpoch,
+		"database_type":               w.metadata.DatabaseType,
+		"description":                 description,
+		"ip_version":                  w.metadata.IPVersion,
+		"languages":                   languages,
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 w.metadata.RecordSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(metadata.encoded), nil
+}
+
+func putNode(buffer []byte, recordSize uint16, left uint, right uint) {
+	switch recordSize {
+	case 24:
+		buffer[0], buffer[1], buffer[2] = byte(left>>16), byte(left>>8), byte(left)
+		buffer[3], buffer[4], buffer[5] = byte(right>>16), byte(right>>8), byte(right)
+	case 28:
+		buffer[0], buffer[1], buffer[2] = byte(left>>16), byte(left>>8), byte(left)
+		buffer[3] = byte(left>>20)&0xF0 | byte(right>>24)&0x0F
+		buffer[4], buffer[5], buffer[6] = byte(right>>16), byte(right>>8), byte(right)
+	default: // case 32:
+		buffer[0], buffer[1], buffer[2], buffer[3] = byte(left>>24), byte(left>>16), byte(left>>8), byte(left)
+		buffer[4], buffer[5], buffer[6], buffer[7] = byte(right>>24), byte(right>>16), byte(right>>8), byte(right)
+	}
+}
@@ -0,0 +1,670 @@
+package writer
+
+import (
+	"bytes"
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/IncSW/geoip2"
+)
+
+func writeDatabase(t *testing.T, writer *Writer) []byte {
+	buffer := &bytes.Buffer{}
+	_, err := writer.WriteTo(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestWriter(t *testing.T) {
+	for _, recordSize := range []uint16{24, 28, 32} {
+		writer, err := New(geoip2.Metadata{
+			DatabaseType: "GeoIP2-City",
+			Languages:    []string{"en"},
+			Description:  map[string]string{"en": "Test"},
+			RecordSize:   recordSize,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		london := map[string]interface{}{
+			"city": map[string]interface{}{
+				"geoname_id": uint32(2643743),
+				"names":      map[string]string{"en": "London"},
+			},
+			"country": map[string]interface{}{
+				"iso_code": "GB",
+				"names":    map[string]string{"en": "United Kingdom"},
+			},
+		}
+		err = writer.Insert(netip.MustParsePrefix("81.2.69.0/24"), london)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writer.Insert(netip.MustParsePrefix("81.2.69.160/27"), map[string]interface{}{
+			"country": map[string]interface{}{
+				"iso_code": "GB",
+				"names":    map[string]string{"en": "United Kingdom"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writer.Insert(netip.MustParsePrefix("2a02:ff80::/29"), map[string]interface{}{
+			"country": map[string]interface{}{
+				"iso_code": "DE",
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reader, err := geoip2.NewCityReader(writeDatabase(t, writer))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = reader.Verify()
+		if err != nil {
+			t.Fatal(err)
+		}
+		metadata := reader.Metadata()
+		if metadata.RecordSize != recordSize || metadata.IPVersion != 6 || metadata.Description["en"] != "Test" || metadata.BuildEpoch == 0 {
+			t.Fatal(metadata)
+		}
+		record, err := reader.Lookup(net.ParseIP("81.2.69.142"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.City.GeoNameID != 2643743 || record.City.Names["en"] != "London" || record.Country.ISOCode != "GB" {
+			t.Fatal(record)
+		}
+		if record.Prefix != netip.MustParsePrefix("81.2.69.128/27") {
+			t.Fatal(record.Prefix)
+		}
+		record, err = reader.Lookup(net.ParseIP("81.2.69.170"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.City.GeoNameID != 0 || record.Country.Names["en"] != "United Kingdom" || record.Prefix != netip.MustParsePrefix("81.2.69.160/27") {
+			t.Fatal(record)
+		}
+		for _, ip := range []string{"::ffff:81.2.69.142", "2002:5102:458e::"} {
+			record, err = reader.LookupAddr(netip.MustParseAddr(ip))
+			if err != nil {
+				t.Fatal(ip, err)
+			}
+			if record.City.GeoNameID != 2643743 {
+				t.Fatal(ip)
+			}
+		}
+		record, err = reader.Lookup(net.ParseIP("2a02:ff80::1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Country.ISOCode != "DE" || record.Prefix != netip.MustParsePrefix("2a02:ff80::/29") {
+			t.Fatal(record)
+		}
+		_, err = reader.Lookup(net.ParseIP("1.1.1.1"))
+		if err != geoip2.ErrNotFound {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWriterIPv4(t *testing.T) {
+	writer, err := New(geoip2.Metadata{
+		DatabaseType: "GeoLite2-ASN",
+		IPVersion:    4,
+		RecordSize:   24,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Insert(netip.MustParsePrefix("2001:db8::/32"), map[string]interface{}{})
+	if err == nil {
+		t.Fatal()
+	}
+	type asn struct {
+		Number       uint32 `maxminddb:"autonomous_system_number"`
+		Organization string `maxminddb:"autonomous_system_organization"`
+	}
+	err = writer.Insert(netip.MustParsePrefix("1.0.0.0/24"), asn{13335, "CLOUDFLARENET"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := geoip2.NewASNReader(writeDatabase(t, writer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := reader.Lookup(net.ParseIP("1.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.AutonomousSystemNumber != 13335 || record.AutonomousSystemOrganization != "CLOUDFLARENET" || record.Network != "1.0.0.0/24" {
+		t.Fatal(record)
+	}
+}
+
+func TestWriterIPv4Aliases(t *testing.T) {
+	writer, err := New(geoip2.Metadata{
+		DatabaseType: "GeoLite2-ASN",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, network := range []string{"::ffff:1.0.0.0/120", "2002:100::/24", "2002::/16"} {
+		err = writer.Insert(netip.MustParsePrefix(network), map[string]interface{}{})
+		if err == nil {
+			t.Fatal(network)
+		}
+	}
+	err = writer.Insert(netip.MustParsePrefix("1.0.0.0/24"), map[string]interface{}{"autonomous_system_number": uint32(13335)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := writeDatabase(t, writer)
+	err = writer.Insert(netip.MustParsePrefix("1.1.1.0/24"), map[string]interface{}{"autonomous_system_number": uint32(13335)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := geoip2.NewASNReader(writeDatabase(t, writer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ip := range []string{"1.0.0.1", "::ffff:1.0.0.1", "2002:100::", "1.1.1.1", "::ffff:1.1.1.1", "2002:101:101::"} {
+		record, err := reader.Lookup(net.ParseIP(ip))
+		if err != nil {
+			t.Fatal(ip, err)
+		}
+		if record.AutonomousSystemNumber != 13335 {
+			t.Fatal(ip, record)
+		}
+	}
+	reader, err = geoip2.NewASNReader(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = reader.Lookup(net.ParseIP("2002:101:101::"))
+	if err != geoip2.ErrNotFound {
+		t.Fatal(err)
+	}
+
+	writer, err = New(geoip2.Metadata{
+		DatabaseType: "GeoLite2-ASN",
+	}, WithoutIPv4Aliases())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = writer.Insert(netip.MustParsePrefix("2002::/16"), map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriterTypes(t *testing.T) {
+	writer, err := New(geoip2.Metadata{
+		DatabaseType: "Test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := string(bytes.Repeat([]byte("x"), 70000))
+	expected := map[string]interface{}{
+		"string":  "string",
+		"long":    long,
+		"float64": 42.5,
+		"float32": float32(1.5),
+		"bytes":   []byte{1, 2, 3},
+		"uint16":  uint16(100),
+		"uint32":  uint32(1 << 30),
+		"uint64":  uint64(1 << 60),
+		"uint128": new(big.Int).Lsh(big.NewInt(1), 100),
+		"int32":   int32(-42),
+		"zero":    int32(0),
+		"true":    true,
+		"false":   false,
+		"map":     map[string]interface{}{"a": "b"},
+		"slice":   []interface{}{"a", uint16(1), map[string]interface{}{}},
+		"empty":   []interface{}{},
+	}
+	err = writer.Insert(netip.MustParsePrefix("::/0"), expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := geoip2.NewReader(writeDatabase(t, writer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record interface{}
+	err = reader.Lookup(net.ParseIP("2001:db8::1"), &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(record, expected) {
+		t.Fatal(record)
+	}
+
+	for _, value := range []interface{}{nil, int64(1 << 40), map[int]string{}, new(big.Int).Lsh(big.NewInt(1), 128), make(chan int)} {
+		err = writer.Insert(netip.MustParsePrefix("::/0"), value)
+		if err == nil {
+			t.Fatal(value)
+		}
+	}
+}
+
+func TestWriterDeduplication(t *testing.T) {
+	writer, err := New(geoip2.Metadata{
+		DatabaseType: "Test",
+		IPVersion:    4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		err = writer.Insert(netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(i >> 8), byte(i), 0}), 24), map[string]interface{}{
+			"name":    "office",
+			"country": "DE",
+			"id":      uint32(i % 10),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	reader, err := geoip2.NewReader(writeDatabase(t, writer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ten distinct records, sharing their keys and strings through pointers.
+	if reader.DataSectionSize() > 200 {
+		t.Fatal(reader.DataSectionSize())
+	}
+	err = reader.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i += 99 {
+		var record struct {
+			Name string `maxminddb:"name"`
+			ID   uint32 `maxminddb:"id"`
+		}
+		err = reader.Lookup(net.IPv4(10, byte(i>>8), byte(i), 1), &record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Name != "office" || record.ID != uint32(i%10) {
+			t.Fatal(strconv.Itoa(i), record)
+		}
+	}
+}
+
+func TestWriterRecordSize(t *testing.T) {
+	_, err := New(geoip2.Metadata{DatabaseType: "Test", RecordSize: 16})
+	if err == nil {
+		t.Fatal()
+	}
+	_, err = New(geoip2.Metadata{DatabaseType: "Test", IPVersion: 5})
+	if err == nil {
+		t.Fatal()
+	}
+	_, err = New(geoip2.Metadata{})
+	if err == nil {
+		t.Fatal()
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base, err := New(geoip2.Metadata{
+		DatabaseType: "GeoIP2-City",
+		Languages:    []string{"en"},
+		RecordSize:   24,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = base.Insert(netip.MustParsePrefix("81.2.69.0/24"), map[string]interface{}{
+		"city": map[string]interface{}{
+			"geoname_id": uint32(2643743),
+			"names":      map[string]string{"en": "London"},
+		},
+		"country": map[string]interface{}{
+			"iso_code": "GB",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseReader, err := geoip2.NewReader(writeDatabase(t, base))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overlay, err := New(geoip2.Metadata{
+		DatabaseType: "Corrections",
+		RecordSize:   24,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = overlay.Insert(netip.MustParsePrefix("81.2.69.128/25"), map[string]interface{}{
+		"city": map[string]interface{}{
+			"names": map[string]string{"en": "Manchester"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = overlay.Insert(netip.MustParsePrefix("10.0.0.0/8"), map[string]interface{}{
+		"country": map[string]interface{}{
+			"iso_code": "DE",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlayReader, err := geoip2.NewReader(writeDatabase(t, overlay))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := NewFromReader(baseReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = merged.MergeNetworks(overlayReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = merged.Merge(netip.MustParsePrefix("81.2.69.0/26"), map[string]interface{}{
+		"traits": map[string]interface{}{
+			"is_legitimate_proxy": true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := geoip2.NewCityReader(writeDatabase(t, merged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = reader.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.Metadata().DatabaseType != "GeoIP2-City" || reader.Metadata().Languages[0] != "en" {
+		t.Fatal(reader.Metadata())
+	}
+	record, err := reader.Lookup(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.City.GeoNameID != 2643743 || record.City.Names["en"] != "Manchester" || record.Country.ISOCode != "GB" || record.Traits.IsLegitimateProxy {
+		t.Fatal(record)
+	}
+	if record.Prefix != netip.MustParsePrefix("81.2.69.128/25") {
+		t.Fatal(record.Prefix)
+	}
+	record, err = reader.Lookup(net.ParseIP("81.2.69.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.City.Names["en"] != "London" || !record.Traits.IsLegitimateProxy || record.Prefix != netip.MustParsePrefix("81.2.69.0/26") {
+		t.Fatal(record)
+	}
+	record, err = reader.Lookup(net.ParseIP("10.1.2.3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Country.ISOCode != "DE" || record.City.GeoNameID != 0 {
+		t.Fatal(record)
+	}
+
+	replaced, err := NewFromReader(baseReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = replaced.InsertNetworks(overlayReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err = geoip2.NewCityReader(writeDatabase(t, replaced))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err = reader.Lookup(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.City.GeoNameID != 0 || record.City.Names["en"] != "Manchester" || record.Country.ISOCode != "" {
+		t.Fatal(record)
+	}
+}
+
+func TestMergeIPv4Aliases(t *testing.T) {
+	source, err := New(geoip2.Metadata{
+		DatabaseType: "GeoIP2-Country",
+	}, WithoutIPv4Aliases())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = source.Insert(netip.MustParsePrefix("2002:1::/32"), map[string]interface{}{
+		"country": map[string]interface{}{
+			"iso_code": "GB",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceReader, err := geoip2.NewReader(writeDatabase(t, source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewFromReader(sourceReader)
+	if err == nil || !strings.Contains(err.Error(), "2002:1::/32") || !strings.Contains(err.Error(), "WithoutIPv4Aliases") {
+		t.Fatal(err)
+	}
+	withAliases, err := New(geoip2.Metadata{
+		DatabaseType: "GeoIP2-Country",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = withAliases.MergeNetworks(sourceReader)
+	if err == nil || !strings.Contains(err.Error(), "2002:1::/32") {
+		t.Fatal(err)
+	}
+
+	merged, err := NewFromReader(sourceReader, WithoutIPv4Aliases())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = merged.MergeNetworks(sourceReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := geoip2.NewCountryReader(writeDatabase(t, merged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := reader.Lookup(net.ParseIP("2002:1::1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Country.ISOCode != "GB" || record.Prefix != netip.MustParsePrefix("2002:1::/32") {
+		t.Fatal(record)
+	}
+}
+
+func TestCSVImporter(t *testing.T) {
+	writer, err := New(geoip2.Metadata{
+		DatabaseType: "GeoLite2-City",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	importer := NewCSVImporter(writer)
+	header := "geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,subdivision_1_iso_code,subdivision_1_name,subdivision_2_iso_code,subdivision_2_name,city_name,metro_code,time_zone,is_in_european_union\n"
+	err = importer.ReadLocations(strings.NewReader(header +
+		"6255148,en,EU,Europe,,,,,,,,,,0\n" +
+		"2635167,en,EU,Europe,GB,\"United Kingdom\",,,,,,,Europe/London,0\n" +
+		"2921044,en,EU,Europe,DE,Germany,,,,,,,Europe/Berlin,1\n" +
+		"2633352,en,EU,Europe,GB,\"United Kingdom\",,,,,,,Europe/London,0\n" +
+		"2643743,en,EU,Europe,GB,\"United Kingdom\",ENG,England,,,London,,Europe/London,0\n" +
+		"3175395,en,EU,Europe,IT,Italy,62,Lazio,,,,,Europe/Rome,1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = importer.ReadLocations(strings.NewReader(header +
+		"6255148,de,EU,Europa,,,,,,,,,,0\n" +
+		"2635167,de,EU,Europa,GB,\"Vereinigtes Königreich\",,,,,,,Europe/London,0\n" +
+		"2921044,de,EU,Europa,DE,Deutschland,,,,,,,Europe/Berlin,1\n" +
+		"2633352,de,EU,Europa,GB,\"Vereinigtes Königreich\",,,,,,,Europe/London,0\n" +
+		"2643743,de,EU,Europa,GB,\"Vereinigtes Königreich\",ENG,England,,,London,,Europe/London,0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = importer.InsertBlocks(strings.NewReader("network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider,postal_code,latitude,longitude,accuracy_radius\n" +
+		"81.2.69.128/26,2643743,2635167,,0,0,EC2V,51.5142,-0.0931,20\n" +
+		"81.2.70.0/24,2635167,2921044,2921044,0,1,,51.4964,-0.1224,500\n" +
+		"81.2.71.0/24,2633352,2635167,,0,0,,,,\n" +
+		"81.2.72.0/24,2633352,,,0,0,,,,\n" +
+		"81.2.73.0/24,3175395,,,0,0,,,,\n" +
+		"2a02:ff80::/29,2921044,2921044,,0,0,,51.2993,9.491,100\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := geoip2.NewCityReader(writeDatabase(t, writer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reader.Metadata().Languages, []string{"de", "en"}) {
+		t.Fatal(reader.Metadata().Languages)
+	}
+
+	europe := geoip2.Continent{
+		GeoNameID: 6255148,
+		Code:      "EU",
+		Names:     map[string]string{"en": "Europe", "de": "Europa"},
+	}
+	unitedKingdom := geoip2.Country{
+		GeoNameID: 2635167,
+		ISOCode:   "GB",
+		Names:     map[string]string{"en": "United Kingdom", "de": "Vereinigtes Königreich"},
+	}
+	germany := geoip2.Country{
+		GeoNameID:         2921044,
+		ISOCode:           "DE",
+		Names:             map[string]string{"en": "Germany", "de": "Deutschland"},
+		IsInEuropeanUnion: true,
+	}
+	for _, test := range []struct {
+		ip       string
+		expected *geoip2.CityResult
+	}{
+		{"81.2.69.142", &geoip2.CityResult{
+			Continent: europe,
+			Country:   unitedKingdom,
+			Subdivisions: []geoip2.Subdivision{{
+				ISOCode: "ENG",
+				Names:   map[string]string{"en": "England", "de": "England"},
+			}},
+			City: geoip2.City{
+				GeoNameID: 2643743,
+				Names:     map[string]string{"en": "London", "de": "London"},
+			},
+			Location: geoip2.Location{
+				Latitude:       51.5142,
+				Longitude:      -0.0931,
+				TimeZone:       "Europe/London",
+				AccuracyRadius: 20,
+			},
+			Postal:            geoip2.Postal{Code: "EC2V"},
+			RegisteredCountry: unitedKingdom,
+			Prefix:            netip.MustParsePrefix("81.2.69.128/26"),
+		}},
+		{"81.2.70.1", &geoip2.CityResult{
+			Continent: europe,
+			Country:   unitedKingdom,
+			Location: geoip2.Location{
+				Latitude:       51.4964,
+				Longitude:      -0.1224,
+				TimeZone:       "Europe/London",
+				AccuracyRadius: 500,
+			},
+			RegisteredCountry:  germany,
+			RepresentedCountry: germany,
+			Traits:             geoip2.Traits{IsSatelliteProvider: true},
+			Prefix:             netip.MustParsePrefix("81.2.70.0/24"),
+		}},
+		{"81.2.71.1", &geoip2.CityResult{
+			Continent:         europe,
+			Country:           unitedKingdom,
+			City:              geoip2.City{GeoNameID: 2633352},
+			Location:          geoip2.Location{TimeZone: "Europe/London"},
+			RegisteredCountry: unitedKingdom,
+			Prefix:            netip.MustParsePrefix("81.2.71.0/24"),
+		}},
+		{"81.2.72.1", &geoip2.CityResult{
+			Continent: europe,
+			Country:   unitedKingdom,
+			City:      geoip2.City{GeoNameID: 2633352},
+			Location:  geoip2.Location{TimeZone: "Europe/London"},
+			Prefix:    netip.MustParsePrefix("81.2.72.0/24"),
+		}},
+		{"81.2.73.1", &geoip2.CityResult{
+			Continent: geoip2.Continent{
+				GeoNameID: 6255148,
+				Code:      "EU",
+				Names:     map[string]string{"en": "Europe"},
+			},
+			Country: geoip2.Country{
+				ISOCode:           "IT",
+				Names:             map[string]string{"en": "Italy"},
+				IsInEuropeanUnion: true,
+			},
+			Subdivisions: []geoip2.Subdivision{{
+				ISOCode: "62",
+				Names:   map[string]string{"en": "Lazio"},
+			}},
+			Location: geoip2.Location{
+				TimeZone: "Europe/Rome",
+			},
+			Prefix: netip.MustParsePrefix("81.2.73.0/24"),
+		}},
+		{"2a02:ff80::1", &geoip2.CityResult{
+			Continent: europe,
+			Country:   germany,
+			Location: geoip2.Location{
+				Latitude:       51.2993,
+				Longitude:      9.491,
+				TimeZone:       "Europe/Berlin",
+				AccuracyRadius: 100,
+			},
+			RegisteredCountry: germany,
+			Prefix:            netip.MustParsePrefix("2a02:ff80::/29"),
+		}},
+	} {
+		record, err := reader.Lookup(net.ParseIP(test.ip))
+		if err != nil {
+			t.Fatal(test.ip, err)
+		}
+		if !reflect.DeepEqual(record, test.expected) {
+			t.Fatal(test.ip, record)
+		}
+	}
+
+	err = importer.InsertBlocks(strings.NewReader("network,geoname_id\n1.0.0.0/24,1\n"))
+	if err == nil || err.Error() != "line 2: unknown geoname_id: 1" {
+		t.Fatal(err)
+	}
+	err = importer.InsertBlocks(strings.NewReader("geoname_id\n"))
+	if err == nil {
+		t.Fatal()
+	}
+}